@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/afalcongonzalez/surfmate.io/internal/config"
+	"github.com/afalcongonzalez/surfmate.io/internal/secrets"
+)
+
+// loadConfig loads and checks the configuration from the config file,
+// environment and the flags parsed on fs
+func loadConfig(path string, fs *flag.FlagSet) (*config.Config, error) {
+	cfg, err := config.Load(path, fs)
+	if err != nil {
+		return nil, err
+	}
+	if err := browser.CheckConfig(cfg); err != nil {
+		return nil, err
+	}
+	for _, name := range secrets.New(cfg.Secrets).Short() {
+		fmt.Fprintf(os.Stderr, "Warning: secret %s is shorter than %d characters, so the same text elsewhere in pages is masked too\n", name, secrets.MinLength)
+	}
+	return cfg, nil
+}
+
+// runConfig implements "surfmate.io config print", which shows the effective
+// configuration after the config file, environment and flags are applied
+func runConfig(args []string) int {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	configPath := config.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: surfmate.io config print [flags]\n")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "print" {
+		fs.Usage()
+		return 2
+	}
+	fs.Parse(args[1:])
+
+	cfg, err := loadConfig(*configPath, fs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		return 1
+	}
+	out, err := cfg.YAML()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		return 1
+	}
+
+	if cfg.File != "" {
+		fmt.Printf("# Config file: %s\n", cfg.File)
+	} else {
+		fmt.Printf("# No config file (looked for %s)\n", config.DefaultPath())
+	}
+	os.Stdout.Write(out)
+	return 0
+}
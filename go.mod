@@ -3,8 +3,10 @@ module github.com/afalcongonzalez/surfmate.io
 go 1.23
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/go-rod/rod v0.116.2
 	github.com/mark3labs/mcp-go v0.18.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
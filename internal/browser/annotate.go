@@ -0,0 +1,133 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// marksOverlayID is the id of the element holding the numbered boxes
+const marksOverlayID = "__surfmate-marks"
+
+// annotateJS numbers every visible, uncovered interactive element with a
+// data-surfmate-ref attribute and draws a labelled box over it. Elements
+// outside the viewport are only included when all is true. Refs from a
+// previous annotation are cleared first.
+const annotateJS = `(all, overlayID) => {` + locatorLibJS + `
+	const interactive = 'a[href], button, input:not([type=hidden]), select, textarea, summary, ' +
+		'[role=button], [role=link], [role=checkbox], [role=radio], [role=tab], [role=menuitem], ' +
+		'[role=option], [role=switch], [role=combobox], [role=textbox], [contenteditable=""], ' +
+		'[contenteditable=true], [onclick], [tabindex]:not([tabindex="-1"])';
+	const colors = ['#e6194b', '#3cb44b', '#4363d8', '#f58231', '#911eb4', '#008080'];
+
+	const everything = descendants(document, true);
+	for (const el of everything) el.removeAttribute('data-surfmate-ref');
+	document.getElementById(overlayID)?.remove();
+
+	const overlay = document.createElement('div');
+	overlay.id = overlayID;
+	overlay.style.cssText = 'position:absolute;left:0;top:0;width:0;height:0;pointer-events:none;z-index:2147483647';
+
+	const legend = [];
+	for (const el of everything) {
+		if (!el.matches(interactive) || !el.checkVisibility({ opacityProperty: true, visibilityProperty: true })) continue;
+		const r = el.getBoundingClientRect();
+		if (r.width < 1 || r.height < 1) continue;
+
+		const inView = r.bottom > 0 && r.right > 0 && r.top < innerHeight && r.left < innerWidth;
+		if (!inView && !all) continue;
+		if (inView) {
+			const x = Math.min(Math.max(r.left + r.width / 2, 0), innerWidth - 1);
+			const y = Math.min(Math.max(r.top + r.height / 2, 0), innerHeight - 1);
+			const hit = el.getRootNode().elementFromPoint(x, y);
+			if (hit && hit !== el && !el.contains(hit) && !hit.contains(el)) continue;
+		}
+
+		const ref = legend.length + 1;
+		const color = colors[ref % colors.length];
+		el.setAttribute('data-surfmate-ref', String(ref));
+		const d = describe(el);
+		legend.push({ ref, role: d.role, text: d.text });
+
+		const left = r.left + scrollX;
+		const top = r.top + scrollY;
+		const box = document.createElement('div');
+		box.style.cssText = 'position:absolute;box-sizing:border-box;border:2px solid ' + color +
+			';left:' + left + 'px;top:' + top + 'px;width:' + r.width + 'px;height:' + r.height + 'px';
+		const label = document.createElement('span');
+		label.textContent = String(ref);
+		label.style.cssText = 'position:absolute;left:-2px;top:' + (top < 18 ? 0 : -18) + 'px;background:' + color +
+			';color:#fff;font:bold 12px/16px monospace;padding:0 3px;border-radius:2px';
+		box.appendChild(label);
+		overlay.appendChild(box);
+	}
+
+	document.documentElement.appendChild(overlay);
+	return legend;
+}`
+
+// removeMarksJS removes the annotation overlay, keeping the refs usable
+const removeMarksJS = `(overlayID) => document.getElementById(overlayID)?.remove()`
+
+// Mark is one numbered element of an annotated screenshot
+type Mark struct {
+	Ref      int    `json:"ref"`
+	Role     string `json:"role"`
+	Text     string `json:"text"`
+	Selector string `json:"selector"`
+}
+
+// FormatLegend renders marks as one line per element for a text response
+func FormatLegend(marks []Mark) string {
+	if len(marks) == 0 {
+		return "No interactive elements found."
+	}
+
+	var b strings.Builder
+	b.WriteString("Numbered elements (use the selector shown to click or type):")
+	for _, mk := range marks {
+		role := mk.Role
+		if role == "" {
+			role = "element"
+		}
+		fmt.Fprintf(&b, "\n[%d] %s %q -> %s", mk.Ref, role, mk.Text, mk.Selector)
+	}
+	return b.String()
+}
+
+// AnnotatedScreenshot captures the page with a numbered box over each visible
+// interactive element and returns the legend for those numbers. The numbers
+// stay usable as ref=N selectors until the next annotation or navigation.
+// opts.Selector is ignored.
+func (m *Manager) AnnotatedScreenshot(opts ScreenshotOptions) ([]byte, []Mark, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.page == nil {
+		return nil, nil, fmt.Errorf("browser is not open")
+	}
+
+	opts.Selector = ""
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	res, err := m.page.Timeout(m.config.BrowserTimeout).Eval(annotateJS, opts.FullPage || opts.Clip != nil, marksOverlayID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to annotate page: %w", err)
+	}
+	defer m.page.Eval(removeMarksJS, marksOverlayID)
+
+	var marks []Mark
+	if err := res.Value.Unmarshal(&marks); err != nil {
+		return nil, nil, err
+	}
+	for i := range marks {
+		marks[i].Selector = fmt.Sprintf("%s=%d", EngineRef, marks[i].Ref)
+	}
+
+	data, err := m.screenshot(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, marks, nil
+}
@@ -0,0 +1,424 @@
+package browser
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/config"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Approval modes: where the user is asked to confirm sensitive actions
+const (
+	ApprovalOff      = "off"
+	ApprovalBrowser  = "browser"
+	ApprovalTerminal = "terminal"
+)
+
+// ApprovalError reports a sensitive action the user did not approve
+type ApprovalError struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+func (e *ApprovalError) Error() string {
+	return fmt.Sprintf("the user did not approve %s (%s)", e.Action, e.Reason)
+}
+
+// approvalRules decide which actions need the user's approval
+type approvalRules struct {
+	mode         string
+	timeout      time.Duration
+	clickText    []string
+	urls         []urlRule
+	paymentForms bool
+}
+
+// configApproval returns the approval rules set in the configuration, or
+// nil when approval is off
+func configApproval(cfg *config.Config) (*approvalRules, error) {
+	if cfg.Approval == "" || cfg.Approval == ApprovalOff {
+		return nil, nil
+	}
+
+	r := &approvalRules{mode: cfg.Approval, timeout: cfg.ApprovalTimeout, paymentForms: cfg.ApprovePaymentForms}
+	for _, text := range config.List(cfg.ApproveClickText) {
+		r.clickText = append(r.clickText, strings.ToLower(text))
+	}
+	for _, pattern := range config.List(cfg.ApproveURLs) {
+		rule, err := parseRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		r.urls = append(r.urls, rule)
+	}
+	return r, nil
+}
+
+// urlNeedsApproval reports whether opening rawURL needs approval
+func (r *approvalRules) urlNeedsApproval(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if r == nil || err != nil {
+		return false
+	}
+	for _, rule := range r.urls {
+		if rule.match(u, rawURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeTargetJS describes the element an action is about to use
+const describeTargetJS = `function() {
+	const label = (this.innerText || this.value || this.getAttribute('aria-label') || this.title || '')
+		.trim().replace(/\s+/g, ' ').slice(0, 80);
+	const link = this.closest('a[href]');
+	const form = this.form || this.closest('form');
+	const payment = !!form && !!form.querySelector(
+		'[autocomplete^="cc-"], input[name*="card" i], input[id*="card" i], input[name*="cvv" i], input[name*="cvc" i]');
+	const submit = !!form && this.matches('button:not([type]), button[type=submit], input[type=submit], input[type=image]');
+	return {label, href: link ? link.href : '', payment, submit};
+}`
+
+// target is what describeTargetJS returns
+type target struct {
+	Label   string `json:"label"`
+	Href    string `json:"href"`
+	Payment bool   `json:"payment"`
+	Submit  bool   `json:"submit"`
+}
+
+func describeTarget(el *rod.Element) (target, error) {
+	var t target
+	res, err := el.Eval(describeTargetJS)
+	if err != nil {
+		return t, err
+	}
+	err = res.Value.Unmarshal(&t)
+	return t, err
+}
+
+// confirmNavigation asks the user before opening a URL that needs approval.
+// Must be called with m.mu held.
+func (m *Manager) confirmNavigation(rawURL string) error {
+	if !m.approvals.urlNeedsApproval(rawURL) {
+		return nil
+	}
+	return m.approve("opening " + rawURL)
+}
+
+// confirmClick asks the user before clicking the element el matching
+// selector, when it matches the approval rules. Once approved, el is
+// checked again, as the page may have changed while the user decided.
+// Must be called with m.mu held.
+func (m *Manager) confirmClick(selector string, el *rod.Element) error {
+	if m.approvals == nil {
+		return nil
+	}
+	t, err := describeTarget(el)
+	if err != nil {
+		return err
+	}
+
+	action := fmt.Sprintf("clicking %q", t.Label)
+	lower := strings.ToLower(t.Label)
+	switch {
+	case slices.ContainsFunc(m.approvals.clickText, func(text string) bool { return strings.Contains(lower, text) }):
+	case m.approvals.paymentForms && t.Payment && t.Submit:
+		action += " to submit a payment form"
+	case t.Href != "" && m.approvals.urlNeedsApproval(t.Href):
+		action += " to open " + t.Href
+	default:
+		return nil
+	}
+	if err := m.approve(action); err != nil {
+		return err
+	}
+	return m.recheck(selector, el, true)
+}
+
+// confirmSubmit asks the user before submitting a payment form by pressing
+// Enter in the element el matching selector. Must be called with m.mu held.
+func (m *Manager) confirmSubmit(selector string, el *rod.Element) error {
+	if m.approvals == nil || !m.approvals.paymentForms {
+		return nil
+	}
+	t, err := describeTarget(el)
+	if err != nil {
+		return err
+	}
+	if !t.Payment {
+		return nil
+	}
+	if err := m.approve("submitting a payment form"); err != nil {
+		return err
+	}
+	return m.recheck(selector, el, false)
+}
+
+// recheck returns an *ElementError unless selector still resolves to el
+// and el is still actionable. approve releases m.mu while the user decides,
+// so el may have been removed or replaced in the meantime.
+// Must be called with m.mu held.
+func (m *Manager) recheck(selector string, el *rod.Element, pointer bool) error {
+	current, err := m.actionableElement(selector, pointer)
+	if err != nil {
+		return err
+	}
+	res, err := el.Eval(`elm => this === elm`, current.Object)
+	if err != nil || !res.Value.Bool() {
+		return &ElementError{
+			Selector: selector,
+			Reason:   ReasonDetached,
+			Matches:  1,
+			Detail:   "the page changed while waiting for approval",
+		}
+	}
+	return nil
+}
+
+// approve asks the user whether to go ahead with action, returning an
+// *ApprovalError unless they approve in time on the same page. Like WaitForUser it releases
+// m.mu while it waits, so other calls, including a batch's, can run in
+// between, and it gives up when the context bound to the page is done. Must
+// be called with m.mu held.
+func (m *Manager) approve(action string) error {
+	page, rules := m.page, m.approvals
+	info, _ := page.Info()
+	message := "Surfmate.io wants to go ahead with " + action
+	if info != nil {
+		if u, err := url.Parse(info.URL); err == nil && u.Host != "" {
+			message += " on " + u.Host
+		}
+	}
+	message += "."
+
+	// Calls in between see the page without this call's context
+	ctx := page.GetContext()
+	m.page = page.Context(context.Background())
+	m.mu.Unlock()
+
+	var approved bool
+	var err error
+	if rules.mode == ApprovalTerminal {
+		approved, err = askTerminal(ctx, message, rules.timeout)
+	} else {
+		approved, err = askInPage(ctx, page, message, rules.timeout)
+	}
+
+	m.mu.Lock()
+	if m.page == nil || m.page.TargetID != page.TargetID {
+		return &ApprovalError{Action: action, Reason: "the browser session ended while waiting"}
+	}
+	m.page = page
+
+	switch {
+	case err != nil:
+		return &ApprovalError{Action: action, Reason: err.Error()}
+	case !approved:
+		return &ApprovalError{Action: action, Reason: "denied"}
+	}
+	if now, _ := page.Info(); info != nil && (now == nil || withoutFragment(now.URL) != withoutFragment(info.URL)) {
+		return &ApprovalError{Action: action, Reason: "the page changed while waiting"}
+	}
+	return nil
+}
+
+// withoutFragment returns rawURL without its #fragment, which changes
+// within the same page
+func withoutFragment(rawURL string) string {
+	u, _, _ := strings.Cut(rawURL, "#")
+	return u
+}
+
+// errApprovalTimeout is the reason given when the user did not answer
+var errApprovalTimeout = errors.New("no answer in time")
+
+// approvalJS shows the approval prompt unless it is already shown and
+// returns the user's decision, or "" while there is none. It runs in an
+// isolated world with a closed shadow root so page scripts can't see or
+// answer it, and ignores synthetic clicks.
+const approvalJS = `(key, message) => {
+	const state = globalThis[key];
+	if (state && (state.decision || state.host.isConnected)) {
+		return state.decision;
+	}
+
+	const host = document.createElement('div');
+	host.style.cssText = 'all: initial; position: fixed; inset: 0; z-index: 2147483647;';
+	const root = host.attachShadow({mode: 'closed'});
+	root.innerHTML = '<style>' +
+		'.backdrop { position: fixed; inset: 0; background: rgba(0, 0, 0, .5); display: flex; align-items: center; justify-content: center; font: 15px/1.4 system-ui, sans-serif; }' +
+		'.box { background: #fff; color: #111; border-radius: 10px; padding: 20px 24px; max-width: 440px; box-shadow: 0 8px 30px rgba(0, 0, 0, .3); }' +
+		'.title { font-weight: 600; margin: 0 0 8px; }' +
+		'.buttons { display: flex; gap: 8px; justify-content: flex-end; margin-top: 16px; }' +
+		'button { font: inherit; padding: 6px 16px; border-radius: 6px; border: 1px solid #999; background: #fff; cursor: pointer; }' +
+		'.approve { background: #1a73e8; border-color: #1a73e8; color: #fff; }' +
+		'</style><div class="backdrop"><div class="box"><p class="title">Approval needed</p><p class="message"></p>' +
+		'<div class="buttons"><button class="deny">Deny</button><button class="approve">Approve</button></div></div></div>';
+	root.querySelector('.message').textContent = message;
+
+	const next = {host, decision: ''};
+	const decide = (decision) => (e) => {
+		if (!e.isTrusted) return;
+		next.decision = decision;
+		host.remove();
+	};
+	root.querySelector('.approve').addEventListener('click', decide('approved'));
+	root.querySelector('.deny').addEventListener('click', decide('denied'));
+	(document.body || document.documentElement).appendChild(host);
+	globalThis[key] = next;
+	return '';
+}`
+
+// dismissApprovalJS removes the approval prompt
+const dismissApprovalJS = `(key) => globalThis[key]?.host.remove()`
+
+// approvalKey names the prompt state in its isolated world
+const approvalKey = "__surfmateApproval"
+
+// askInPage shows message with Approve and Deny buttons over the page and
+// polls until the user picks one, timeout passes or ctx is done. The prompt
+// is shown again if the page navigates away.
+func askInPage(ctx context.Context, page *rod.Page, message string, timeout time.Duration) (bool, error) {
+	page = page.Context(ctx)
+	deadline := time.Now().Add(timeout)
+	pollInterval := 500 * time.Millisecond
+
+	var world proto.RuntimeExecutionContextID
+	eval := func(js string, args ...any) (string, error) {
+		if world == 0 {
+			w, err := proto.PageCreateIsolatedWorld{FrameID: page.FrameID, WorldName: "surfmate-approval"}.Call(page)
+			if err != nil {
+				return "", err
+			}
+			world = w.ExecutionContextID
+		}
+		params, _ := json.Marshal(args)
+		res, err := proto.RuntimeEvaluate{
+			Expression:    fmt.Sprintf("(%s)(...%s)", js, params),
+			ContextID:     world,
+			ReturnByValue: true,
+		}.Call(page)
+		if err != nil || res.ExceptionDetails != nil {
+			// The world went away with the page it belonged to
+			world = 0
+			return "", fmt.Errorf("failed to show the approval prompt")
+		}
+		return res.Result.Value.Str(), nil
+	}
+	defer func() {
+		// Dismiss even when ctx is done
+		page = page.Context(context.Background())
+		_, _ = eval(dismissApprovalJS, approvalKey)
+	}()
+
+	for time.Now().Before(deadline) {
+		switch decision, _ := eval(approvalJS, approvalKey, message); decision {
+		case "approved":
+			return true, nil
+		case "denied":
+			return false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+	return false, errApprovalTimeout
+}
+
+// terminal reads answers from the controlling terminal. A read blocked on
+// a tty can't be interrupted reliably, so one reader lives for the rest of
+// the process instead of one per question.
+var terminal struct {
+	once  sync.Once
+	lines chan string
+	err   error
+}
+
+// terminalLines returns the lines typed on the controlling terminal, which
+// is not stdin when serving MCP over stdio. The channel is closed when the
+// terminal goes away.
+func terminalLines() (<-chan string, error) {
+	terminal.once.Do(func() {
+		in := "/dev/tty"
+		if runtime.GOOS == "windows" {
+			in = "CONIN$"
+		}
+		r, err := os.Open(in)
+		if err != nil {
+			terminal.err = fmt.Errorf("no terminal to ask on: %w", err)
+			return
+		}
+		terminal.lines = make(chan string)
+		go func() {
+			defer close(terminal.lines)
+			reader := bufio.NewReader(r)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				terminal.lines <- line
+			}
+		}()
+	})
+	return terminal.lines, terminal.err
+}
+
+// askTerminal asks on the controlling terminal until the user answers,
+// timeout passes or ctx is done
+func askTerminal(ctx context.Context, message string, timeout time.Duration) (bool, error) {
+	lines, err := terminalLines()
+	if err != nil {
+		return false, err
+	}
+	out := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		out = "CONOUT$"
+	}
+	w, err := os.OpenFile(out, os.O_WRONLY, 0)
+	if err != nil {
+		return false, fmt.Errorf("no terminal to ask on: %w", err)
+	}
+	defer w.Close()
+
+	// An answer typed after an earlier question timed out is not this one's
+	for drained := false; !drained; {
+		select {
+		case <-lines:
+		default:
+			drained = true
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s\nApprove? [y/N] ", message)
+	select {
+	case line, ok := <-lines:
+		if !ok {
+			return false, fmt.Errorf("the terminal closed")
+		}
+		a := strings.ToLower(strings.TrimSpace(line))
+		return a == "y" || a == "yes", nil
+	case <-ctx.Done():
+		fmt.Fprintln(w, "\nCancelled, denied.")
+		return false, ctx.Err()
+	case <-time.After(timeout):
+		fmt.Fprintln(w, "\nNo answer, denied.")
+		return false, errApprovalTimeout
+	}
+}
@@ -0,0 +1,188 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Batch action types
+const (
+	ActionNavigate   = "navigate"
+	ActionClick      = "click"
+	ActionType       = "type"
+	ActionWaitFor    = "wait_for"
+	ActionExtract    = "extract"
+	ActionScreenshot = "screenshot"
+)
+
+// Action is one step of a batch run by RunActions. Only the fields used by
+// the action type are read.
+type Action struct {
+	Action          string `json:"action"`
+	URL             string `json:"url,omitempty"`
+	Selector        string `json:"selector,omitempty"`
+	Text            string `json:"text,omitempty"`
+	Submit          bool   `json:"submit,omitempty"`
+	Multiple        bool   `json:"multiple,omitempty"`
+	TimeoutMS       int    `json:"timeout_ms,omitempty"`
+	FullPage        bool   `json:"full_page,omitempty"`
+	Format          string `json:"format,omitempty"`
+	Quality         int    `json:"quality,omitempty"`
+	MaxWidth        int    `json:"max_width,omitempty"`
+	MaxHeight       int    `json:"max_height,omitempty"`
+	ContinueOnError bool   `json:"continue_on_error,omitempty"`
+}
+
+// screenshotOptions returns the options of a screenshot action
+func (a Action) screenshotOptions() ScreenshotOptions {
+	return ScreenshotOptions{
+		FullPage:  a.FullPage,
+		Selector:  a.Selector,
+		Format:    a.Format,
+		Quality:   a.Quality,
+		MaxWidth:  a.MaxWidth,
+		MaxHeight: a.MaxHeight,
+	}
+}
+
+// validate checks that the fields required by the action type are set
+func (a Action) validate() error {
+	switch a.Action {
+	case ActionNavigate:
+		if a.URL == "" {
+			return fmt.Errorf("url is required")
+		}
+	case ActionClick, ActionType, ActionWaitFor, ActionExtract:
+		if a.Selector == "" {
+			return fmt.Errorf("selector is required")
+		}
+	case ActionScreenshot:
+		opts := a.screenshotOptions()
+		return opts.Validate()
+	default:
+		return fmt.Errorf("unknown action %q: use navigate, click, type, wait_for, extract or screenshot", a.Action)
+	}
+	if a.TimeoutMS < 0 {
+		return fmt.Errorf("timeout_ms must not be negative")
+	}
+	return nil
+}
+
+// ActionResult is the outcome of one batch step. Image holds the screenshot
+// of a screenshot step and is base64 encoded in JSON.
+type ActionResult struct {
+	Step     int      `json:"step"`
+	Action   string   `json:"action"`
+	OK       bool     `json:"ok"`
+	Error    string   `json:"error,omitempty"`
+	Reason   string   `json:"reason,omitempty" description:"Element failure reason, as in Error"`
+	URL      string   `json:"url,omitempty" description:"Page URL after the step"`
+	Title    string   `json:"title,omitempty" description:"Page title after navigate"`
+	Texts    []string `json:"texts,omitempty"`
+	Image    []byte   `json:"image,omitempty" description:"Base64 encoded screenshot"`
+	MIMEType string   `json:"mimeType,omitempty"`
+}
+
+// ValidateActions checks every action of a batch before any of them runs
+func ValidateActions(actions []Action) error {
+	if len(actions) == 0 {
+		return fmt.Errorf("no actions given")
+	}
+	for i, a := range actions {
+		if err := a.validate(); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// RunActions validates and runs actions in order while holding the browser
+// for the whole batch, except while a step waits for the user's approval,
+// calling report, when not nil, before each step. A failed step stops the
+// batch unless it sets ContinueOnError, so the results may be fewer than the
+// actions. When ctx is done the batch stops and returns the results so far
+// with ctx's error.
+func (m *Manager) RunActions(ctx context.Context, actions []Action, report func(step int, a Action)) ([]ActionResult, error) {
+	if err := ValidateActions(actions); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.page == nil {
+		return nil, fmt.Errorf("browser is not open")
+	}
+	defer m.bind(ctx)()
+
+	results := make([]ActionResult, 0, len(actions))
+	for i, a := range actions {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		if report != nil {
+			report(i+1, a)
+		}
+
+		res := m.runAction(a)
+		res.Step = i + 1
+		if info, err := m.page.Info(); err == nil {
+			res.URL = info.URL
+		}
+		results = append(results, res)
+
+		if !res.OK && !a.ContinueOnError {
+			break
+		}
+	}
+	return results, ctx.Err()
+}
+
+// runAction runs a single validated action.
+// Must be called with m.mu held.
+func (m *Manager) runAction(a Action) ActionResult {
+	res := ActionResult{Action: a.Action}
+
+	timeout := m.config.BrowserTimeout
+	if a.TimeoutMS > 0 {
+		timeout = time.Duration(a.TimeoutMS) * time.Millisecond
+	}
+
+	var err error
+	switch a.Action {
+	case ActionNavigate:
+		if err = m.navigate(a.URL); err == nil {
+			err = m.page.Timeout(m.config.BrowserTimeout).WaitLoad()
+		}
+		if info, ierr := m.page.Info(); err == nil && ierr == nil {
+			res.Title = info.Title
+		}
+	case ActionClick:
+		err = m.click(a.Selector)
+	case ActionType:
+		err = m.typeText(a.Selector, a.Text, a.Submit)
+	case ActionWaitFor:
+		err = m.waitFor(a.Selector, timeout)
+	case ActionExtract:
+		res.Texts, err = m.extractText(a.Selector, a.Multiple)
+	case ActionScreenshot:
+		opts := a.screenshotOptions()
+		res.Image, err = m.screenshot(opts)
+		res.MIMEType = opts.MIMEType()
+	}
+
+	if err != nil {
+		res.Error = err.Error()
+		var elErr *ElementError
+		if errors.As(err, &elErr) {
+			res.Reason = elErr.Reason
+		}
+		res.Image = nil
+		res.MIMEType = ""
+		return res
+	}
+	res.OK = true
+	return res
+}
@@ -1,22 +1,40 @@
 package browser
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/afalcongonzalez/surfmate.io/internal/config"
+	"github.com/afalcongonzalez/surfmate.io/internal/secrets"
 	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/cdp"
 	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/launcher/flags"
 	"github.com/go-rod/rod/lib/proto"
 )
 
 // Manager handles the browser instance and page operations
 type Manager struct {
-	browser *rod.Browser
-	page    *rod.Page
-	config  *config.Config
-	mu      sync.Mutex
+	browser        *rod.Browser
+	page           *rod.Page
+	config         *config.Config
+	recorder       *Recorder
+	emulation      Emulation
+	remote         *cdp.WebSocket                // set when attached to a running browser
+	endpoint       string                        // DevTools URL of an attached browser
+	health         *health                       // state of the current session
+	proxy          Proxy                         // set with SetProxy for this session
+	browserContext proto.BrowserBrowserContextID // proxied context of the page, if any
+	policy         *Policy                       // URL policy of the current session
+	interceptor    *interceptor                  // holds navigations to the policy
+	approvals      *approvalRules                // actions needing approval, nil when off
+	secrets        *secrets.Store                // typed by reference, masked in output
+	listeners      listeners                     // called on page events
+	mu             sync.Mutex
 }
 
 var (
@@ -27,26 +45,101 @@ var (
 // GetManager returns the singleton browser manager
 func GetManager(cfg *config.Config) *Manager {
 	once.Do(func() {
-		instance = &Manager{config: cfg}
+		instance = &Manager{config: cfg, secrets: secrets.New(cfg.Secrets)}
 	})
 	return instance
 }
 
-// Launch starts the browser with the configured settings
+// Secrets returns the secrets that can be typed by reference and are masked
+// in output
+func (m *Manager) Secrets() *secrets.Store {
+	return m.secrets
+}
+
+// CheckConfig reports configured settings the browser can't use, so they
+// fail at startup rather than on the first launch
+func CheckConfig(cfg *config.Config) error {
+	if cfg.Geolocation != "" {
+		if _, err := ParseGeolocation(cfg.Geolocation); err != nil {
+			return fmt.Errorf("invalid configuration: geolocation: %w", err)
+		}
+	}
+	if _, err := ParseProxy(cfg.ProxyServer, cfg.ProxyBypass, cfg.ProxyUsername, cfg.ProxyPassword); err != nil {
+		return fmt.Errorf("invalid configuration: proxy_server: %w", err)
+	}
+	if _, err := configPolicy(cfg); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if _, err := configApproval(cfg); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if cfg.ExtensionsDir != "" {
+		if _, err := extensionDirs(cfg.ExtensionsDir); err != nil {
+			return fmt.Errorf("invalid configuration: extensions_dir: %w", err)
+		}
+	}
+	return nil
+}
+
+// Launch starts the browser with the configured settings. A browser that
+// crashed or disconnected is replaced.
 func (m *Manager) Launch() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if m.browser != nil {
-		return nil
+		if m.open() {
+			return nil
+		}
+		_ = m.teardown()
+	}
+	if m.config.CDPURL != "" {
+		return m.connect(m.config.CDPURL)
 	}
+	return m.launch()
+}
 
+// launch must be called with m.mu held
+func (m *Manager) launch() error {
 	l := launcher.New().
 		Headless(m.config.Headless).
 		Set("disable-gpu", "false").
 		Set("no-sandbox", "").
 		Set("disable-dev-shm-usage", "")
 
+	proxy, err := m.configProxy()
+	if err != nil {
+		return err
+	}
+	if proxy.Server != "" {
+		l = l.Set("proxy-server", proxy.Server)
+	}
+	if proxy.Bypass != "" {
+		l = l.Set("proxy-bypass-list", proxy.Bypass)
+	}
+
+	if m.config.ExtensionsDir != "" {
+		dirs, err := extensionDirs(m.config.ExtensionsDir)
+		if err != nil {
+			return err
+		}
+		l = l.Set("load-extension", dirs...).Set("disable-extensions-except", dirs...)
+		// The old headless mode can't run extensions
+		if m.config.Headless {
+			l = l.HeadlessNew(true)
+		}
+	}
+
+	// Extra flags come last so they can override the defaults above
+	for _, f := range strings.Fields(m.config.BrowserFlags) {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(f, "-"), "=")
+		if hasValue {
+			l = l.Set(flags.Flag(name), value)
+		} else {
+			l = l.Set(flags.Flag(name))
+		}
+	}
+
 	// Use host browser if configured or detected
 	browserPath := m.config.BrowserPath
 	if browserPath == "" {
@@ -61,41 +154,246 @@ func (m *Manager) Launch() error {
 		return fmt.Errorf("failed to launch browser: %w", err)
 	}
 
+	// A half-started session is never left behind for the next Launch to
+	// mistake for a healthy one
+	fail := func(err error) error {
+		_ = m.teardown()
+		l.Kill()
+		return err
+	}
+
 	m.browser = rod.New().ControlURL(url)
 	if err := m.browser.Connect(); err != nil {
-		return fmt.Errorf("failed to connect to browser: %w", err)
+		return fail(fmt.Errorf("failed to connect to browser: %w", err))
 	}
 
 	// Create initial page
-	m.page, err = m.browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if m.proxy.Server != "" {
+		m.page, m.browserContext, err = proxiedPage(m.browser, m.proxy)
+	} else {
+		m.page, err = m.browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create page: %w", err)
+		return fail(fmt.Errorf("failed to create page: %w", err))
 	}
 
-	// Set viewport
-	err = m.page.SetViewport(&proto.EmulationSetDeviceMetricsOverride{
-		Width:  m.config.ViewportWidth,
-		Height: m.config.ViewportHeight,
-	})
+	if err := m.setup(proxy); err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+// Connect attaches to an already running Chrome instead of launching one and
+// drives one of its open tabs, keeping its logins. endpoint is a DevTools
+// URL such as ws://host:9222/devtools/browser/<id>, http://host:9222 or a
+// port. Close detaches and leaves the browser running.
+func (m *Manager) Connect(endpoint string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.browser != nil {
+		if m.open() {
+			return nil
+		}
+		_ = m.teardown()
+	}
+	return m.connect(endpoint)
+}
+
+// connect must be called with m.mu held
+func (m *Manager) connect(endpoint string) error {
+	u := endpoint
+	if !strings.Contains(endpoint, "/devtools/") {
+		resolved, err := launcher.ResolveURL(endpoint)
+		if err != nil {
+			return fmt.Errorf("failed to find browser at %s: %w", endpoint, err)
+		}
+		u = resolved
+	}
+
+	// Own the websocket so Close can detach without closing the browser
+	ws := &cdp.WebSocket{}
+	if err := ws.Connect(context.Background(), u, nil); err != nil {
+		return fmt.Errorf("failed to connect to browser at %s: %w", endpoint, err)
+	}
+	b := rod.New().Client(cdp.New().Start(ws))
+	if err := b.Connect(); err != nil {
+		_ = ws.Close()
+		return fmt.Errorf("failed to connect to browser at %s: %w", endpoint, err)
+	}
+
+	// A proxy needs a browser context of its own; otherwise reuse a tab and
+	// its logins
+	proxy, err := m.sessionProxy(true)
+	if err != nil {
+		_ = ws.Close()
+		return err
+	}
+	var page *rod.Page
+	if proxy.Server != "" {
+		page, m.browserContext, err = proxiedPage(b, proxy)
+	} else {
+		page, err = existingTab(b)
+	}
+	if err != nil {
+		_ = ws.Close()
+		return err
+	}
+
+	m.browser, m.page, m.remote, m.endpoint = b, page, ws, endpoint
+	return m.setup(proxy)
+}
+
+// existingTab returns the first ordinary tab of b, opening one if there is
+// none
+func existingTab(b *rod.Browser) (*rod.Page, error) {
+	targets, err := proto.TargetGetTargets{}.Call(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tabs: %w", err)
+	}
+	for _, t := range targets.TargetInfos {
+		if t.Type != proto.TargetTargetInfoTypePage || strings.HasPrefix(t.URL, "devtools://") ||
+			strings.HasPrefix(t.URL, "chrome-extension://") {
+			continue
+		}
+		page, err := b.PageFromTarget(t.TargetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach to tab: %w", err)
+		}
+		_, _ = page.Activate()
+		return page, nil
+	}
+
+	page, err := b.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+	return page, nil
+}
+
+// setup watches a new m.page, holds its navigations to the URL policy,
+// saves its downloads and applies the configured emulation and recording to
+// it, answering authentication requests of proxy, which is the proxy the
+// browser or page was created with. The session proxy takes precedence. When
+// any of it fails the session is torn down. Must be called with m.mu held.
+func (m *Manager) setup(proxy Proxy) (err error) {
+	defer func() {
+		if err != nil {
+			_ = m.teardown()
+		}
+	}()
+
+	m.health = watch(m.page)
+
+	if m.proxy.Server != "" {
+		proxy = m.proxy
+	}
+	policy, err := configPolicy(m.config)
+	if err != nil {
+		return err
+	}
+	if m.interceptor, err = intercept(m.page, policy, proxy); err != nil {
+		return err
+	}
+	m.policy = policy
+	if m.approvals, err = configApproval(m.config); err != nil {
+		return err
+	}
+	m.watchLoads(m.page)
+	if err := m.saveDownloads(); err != nil {
+		return err
+	}
+
+	// Set viewport and the configured region overrides
+	emulation, err := m.initialEmulation()
 	if err != nil {
-		return fmt.Errorf("failed to set viewport: %w", err)
+		return err
+	}
+	if err := m.applyEmulation(emulation); err != nil {
+		return err
+	}
+	m.emulation = emulation
+
+	if m.config.Record {
+		if err := m.startRecording(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// IsLaunched returns true if the browser is running
+// initialEmulation returns the emulation configured for new pages. Tabs of
+// an attached browser keep their window size.
+func (m *Manager) initialEmulation() (Emulation, error) {
+	e := Emulation{
+		Width:     m.config.ViewportWidth,
+		Height:    m.config.ViewportHeight,
+		UserAgent: m.config.UserAgent,
+		Locale:    strings.ReplaceAll(m.config.Locale, "_", "-"),
+		Timezone:  m.config.Timezone,
+	}
+	if m.config.Geolocation != "" {
+		g, err := ParseGeolocation(m.config.Geolocation)
+		if err != nil {
+			return e, err
+		}
+		e.Geolocation = g
+	}
+	if m.remote != nil {
+		e.Width, e.Height = 0, 0
+	}
+	return e, e.Validate()
+}
+
+// IsLaunched returns true if the browser is running and has not crashed or
+// disconnected
 func (m *Manager) IsLaunched() bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.browser != nil
+	return m.open()
 }
 
-// Close shuts down the browser
+// open reports whether a session is fully set up and has not crashed or
+// disconnected. A session without its URL policy never counts as open.
+// Must be called with m.mu held.
+func (m *Manager) open() bool {
+	return m.browser != nil && m.page != nil && m.policy != nil && m.health.reason() == ""
+}
+
+// Close shuts down the browser, or detaches from it when it was attached
+// with Connect
 func (m *Manager) Close() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.teardown()
+}
+
+// teardown saves any recording and closes or detaches from the browser.
+// Must be called with m.mu held.
+func (m *Manager) teardown() error {
+	if m.recorder != nil {
+		if path, _, err := m.stopRecording("", ""); err == nil {
+			fmt.Fprintf(os.Stderr, "Recording saved to %s\n", path)
+		}
+	}
+
+	// The watcher must not report the browser we are closing as lost
+	m.health.end()
+	defer func() {
+		m.browser, m.page, m.remote, m.endpoint, m.health = nil, nil, nil, "", nil
+		m.browserContext, m.policy, m.interceptor, m.approvals = "", nil, nil, nil
+	}()
+
+	// Detach from an attached browser, which drops this session's overrides,
+	// leaving no proxied context behind
+	if m.remote != nil {
+		if m.browserContext != "" {
+			_ = proto.TargetDisposeBrowserContext{BrowserContextID: m.browserContext}.Call(m.browser)
+		}
+		return m.remote.Close()
+	}
 	if m.browser != nil {
 		return m.browser.Close()
 	}
@@ -109,19 +407,61 @@ func (m *Manager) Page() *rod.Page {
 	return m.page
 }
 
-// Navigate goes to the specified URL
-func (m *Manager) Navigate(url string) error {
+// bind makes the page calls of an operation fail once ctx is done, until
+// the returned function restores the page. A page replaced in the meantime,
+// while approve released m.mu, is left alone. Must be called with m.mu held.
+func (m *Manager) bind(ctx context.Context) func() {
+	if m.page == nil {
+		return func() {}
+	}
+	page := m.page
+	m.page = page.Context(ctx)
+	return func() {
+		if m.page != nil && m.page.TargetID == page.TargetID {
+			m.page = page
+		}
+	}
+}
+
+// Navigate goes to the specified URL, giving up when ctx is done
+func (m *Manager) Navigate(ctx context.Context, url string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	return m.page.Timeout(m.config.BrowserTimeout).Navigate(url)
+	if m.page == nil {
+		return fmt.Errorf("browser is not open")
+	}
+	defer m.bind(ctx)()
+	return m.navigate(url)
+}
+
+// navigate must be called with m.mu held
+func (m *Manager) navigate(url string) error {
+	if err := m.policy.Check(url); err != nil {
+		return err
+	}
+	if err := m.confirmNavigation(url); err != nil {
+		return err
+	}
+
+	// A redirect to a blocked URL fails the navigation
+	m.interceptor.reset()
+	err := m.page.Timeout(m.config.BrowserTimeout).Navigate(url)
+	if blocked := m.interceptor.violation(); blocked != nil {
+		return blocked
+	}
+	return err
 }
 
-// WaitLoad waits for the page to finish loading
-func (m *Manager) WaitLoad() error {
+// WaitLoad waits for the page to finish loading, giving up when ctx is done
+func (m *Manager) WaitLoad(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.page == nil {
+		return fmt.Errorf("browser is not open")
+	}
+	defer m.bind(ctx)()
 	return m.page.Timeout(m.config.BrowserTimeout).WaitLoad()
 }
 
@@ -130,6 +470,10 @@ func (m *Manager) GetTitle() (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.page == nil {
+		return "", fmt.Errorf("browser is not open")
+	}
+
 	info, err := m.page.Info()
 	if err != nil {
 		return "", err
@@ -142,6 +486,10 @@ func (m *Manager) GetURL() (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.page == nil {
+		return "", fmt.Errorf("browser is not open")
+	}
+
 	info, err := m.page.Info()
 	if err != nil {
 		return "", err
@@ -154,11 +502,28 @@ func (m *Manager) Click(selector string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	el, err := m.page.Timeout(m.config.BrowserTimeout).Element(selector)
+	if m.page == nil {
+		return fmt.Errorf("browser is not open")
+	}
+
+	return m.click(selector)
+}
+
+// click must be called with m.mu held
+func (m *Manager) click(selector string) error {
+	el, err := m.actionableElement(selector, true)
 	if err != nil {
-		return fmt.Errorf("element not found: %s", selector)
+		return err
+	}
+	if err := m.confirmClick(selector, el); err != nil {
+		return err
 	}
-	return el.Click(proto.InputMouseButtonLeft, 1)
+
+	m.interceptor.reset()
+	if err := el.Click(proto.InputMouseButtonLeft, 1); err != nil {
+		return err
+	}
+	return m.interceptor.violation()
 }
 
 // Type types text into an element matching the selector
@@ -166,9 +531,22 @@ func (m *Manager) Type(selector, text string, submit bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	el, err := m.page.Timeout(m.config.BrowserTimeout).Element(selector)
+	if m.page == nil {
+		return fmt.Errorf("browser is not open")
+	}
+
+	return m.typeText(selector, text, submit)
+}
+
+// typeText must be called with m.mu held
+func (m *Manager) typeText(selector, text string, submit bool) error {
+	text, err := m.secrets.Resolve(text)
+	if err != nil {
+		return err
+	}
+	el, err := m.actionableElement(selector, false)
 	if err != nil {
-		return fmt.Errorf("element not found: %s", selector)
+		return err
 	}
 
 	if err := el.Input(text); err != nil {
@@ -176,7 +554,14 @@ func (m *Manager) Type(selector, text string, submit bool) error {
 	}
 
 	if submit {
-		return m.page.Keyboard.Press(13) // Enter key
+		if err := m.confirmSubmit(selector, el); err != nil {
+			return err
+		}
+		m.interceptor.reset()
+		if err := m.page.Keyboard.Press(13); err != nil { // Enter key
+			return err
+		}
+		return m.interceptor.violation()
 	}
 	return nil
 }
@@ -186,10 +571,14 @@ func (m *Manager) Scroll(direction string, amount int, selector string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.page == nil {
+		return fmt.Errorf("browser is not open")
+	}
+
 	if selector != "" {
-		el, err := m.page.Timeout(m.config.BrowserTimeout).Element(selector)
+		el, err := m.element(selector)
 		if err != nil {
-			return fmt.Errorf("element not found: %s", selector)
+			return err
 		}
 		return el.ScrollIntoView()
 	}
@@ -216,6 +605,10 @@ func (m *Manager) GetContent(includeHTML bool) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.page == nil {
+		return "", fmt.Errorf("browser is not open")
+	}
+
 	if includeHTML {
 		return m.page.HTML()
 	}
@@ -226,30 +619,16 @@ func (m *Manager) GetContent(includeHTML bool) (string, error) {
 	return el.Text()
 }
 
-// Screenshot captures the page as a base64 encoded image
-func (m *Manager) Screenshot(fullPage bool, selector string, quality int) ([]byte, error) {
+// Screenshot captures the page, a region or an element as an image
+func (m *Manager) Screenshot(opts ScreenshotOptions) ([]byte, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if selector != "" {
-		el, err := m.page.Timeout(m.config.BrowserTimeout).Element(selector)
-		if err != nil {
-			return nil, fmt.Errorf("element not found: %s", selector)
-		}
-		return el.Screenshot(proto.PageCaptureScreenshotFormatPng, quality)
-	}
-
-	if fullPage {
-		return m.page.Screenshot(true, &proto.PageCaptureScreenshot{
-			Format:  proto.PageCaptureScreenshotFormatPng,
-			Quality: &quality,
-		})
+	if m.page == nil {
+		return nil, fmt.Errorf("browser is not open")
 	}
 
-	return m.page.Screenshot(false, &proto.PageCaptureScreenshot{
-		Format:  proto.PageCaptureScreenshotFormatPng,
-		Quality: &quality,
-	})
+	return m.screenshot(opts)
 }
 
 // ExtractText extracts text from elements matching the selector
@@ -257,10 +636,19 @@ func (m *Manager) ExtractText(selector string, multiple bool) ([]string, error)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.page == nil {
+		return nil, fmt.Errorf("browser is not open")
+	}
+
+	return m.extractText(selector, multiple)
+}
+
+// extractText must be called with m.mu held
+func (m *Manager) extractText(selector string, multiple bool) ([]string, error) {
 	if multiple {
-		elements, err := m.page.Timeout(m.config.BrowserTimeout).Elements(selector)
+		elements, err := m.elements(selector)
 		if err != nil {
-			return nil, fmt.Errorf("elements not found: %s", selector)
+			return nil, err
 		}
 		var texts []string
 		for _, el := range elements {
@@ -272,9 +660,9 @@ func (m *Manager) ExtractText(selector string, multiple bool) ([]string, error)
 		return texts, nil
 	}
 
-	el, err := m.page.Timeout(m.config.BrowserTimeout).Element(selector)
+	el, err := m.element(selector)
 	if err != nil {
-		return nil, fmt.Errorf("element not found: %s", selector)
+		return nil, err
 	}
 	text, err := el.Text()
 	if err != nil {
@@ -283,13 +671,40 @@ func (m *Manager) ExtractText(selector string, multiple bool) ([]string, error)
 	return []string{text}, nil
 }
 
-// WaitForUser waits for user intervention (e.g., captcha solving)
-func (m *Manager) WaitForUser(timeout time.Duration) error {
+// waitFor waits up to timeout for an element matching the selector to be
+// visible, for the wait_for batch action.
+// Must be called with m.mu held.
+func (m *Manager) waitFor(selector string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	el, err := m.elementWithin(selector, timeout)
+	if err != nil {
+		return err
+	}
+	if err := el.Timeout(time.Until(deadline)).WaitVisible(); err != nil {
+		if ctxErr := m.page.GetContext().Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return &ElementError{
+			Selector: selector,
+			Reason:   ReasonHidden,
+			Detail:   fmt.Sprintf("element did not become visible within %v", timeout),
+		}
+	}
+	return nil
+}
+
+// WaitForUser waits for user intervention (e.g., captcha solving) until the
+// captcha is gone, timeout passes or ctx is done, reporting the time elapsed
+// while it waits as WaitForCaptchaResolution does
+func (m *Manager) WaitForUser(ctx context.Context, timeout time.Duration, report func(elapsed time.Duration)) error {
 	m.mu.Lock()
 	page := m.page
 	m.mu.Unlock()
 
-	return WaitForCaptchaResolution(page, timeout)
+	if page == nil {
+		return fmt.Errorf("browser is not open")
+	}
+	return WaitForCaptchaResolution(ctx, page, timeout, report)
 }
 
 // HasCaptcha checks if a captcha is present on the page
@@ -298,5 +713,5 @@ func (m *Manager) HasCaptcha() bool {
 	page := m.page
 	m.mu.Unlock()
 
-	return DetectCaptcha(page)
+	return page != nil && DetectCaptcha(page)
 }
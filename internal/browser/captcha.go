@@ -1,6 +1,7 @@
 package browser
 
 import (
+	"context"
 	"time"
 
 	"github.com/go-rod/rod"
@@ -36,16 +37,39 @@ func DetectCaptcha(page *rod.Page) bool {
 	return false
 }
 
-// WaitForCaptchaResolution polls until the captcha element disappears or timeout
-func WaitForCaptchaResolution(page *rod.Page, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
+// WaitProgressInterval is how often WaitForCaptchaResolution reports
+// progress
+const WaitProgressInterval = 5 * time.Second
+
+// WaitForCaptchaResolution polls until the captcha element disappears,
+// timeout passes or ctx is done. While the captcha is still shown it calls
+// report, when not nil, with the time elapsed every WaitProgressInterval.
+func WaitForCaptchaResolution(ctx context.Context, page *rod.Page, timeout time.Duration, report func(elapsed time.Duration)) error {
+	start := time.Now()
+	deadline := start.Add(timeout)
 	pollInterval := 500 * time.Millisecond
+	lastReport := start
+	page = page.Context(ctx)
 
 	for time.Now().Before(deadline) {
-		if !DetectCaptcha(page) {
+		// A cancelled page finds no captcha
+		captcha := DetectCaptcha(page)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !captcha {
 			return nil
 		}
-		time.Sleep(pollInterval)
+		if report != nil && time.Since(lastReport) >= WaitProgressInterval {
+			lastReport = time.Now()
+			report(time.Since(start))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
 	}
 
 	return nil // Return nil even on timeout - user may have navigated away
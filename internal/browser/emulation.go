@@ -0,0 +1,397 @@
+package browser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// DeviceDefault restores the viewport the browser was launched with, or the
+// window size of an attached browser
+const DeviceDefault = "default"
+
+// Color schemes for media emulation
+const (
+	ColorSchemeLight = "light"
+	ColorSchemeDark  = "dark"
+)
+
+// maxViewportSize is the largest width or height Chrome accepts
+const maxViewportSize = 10000000
+
+// Device is a preset of screen and browser properties
+type Device struct {
+	Width       int
+	Height      int
+	ScaleFactor float64
+	Mobile      bool
+	Touch       bool
+	UserAgent   string
+}
+
+const (
+	iOSUserAgent     = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	iPadUserAgent    = "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	androidUserAgent = "Mozilla/5.0 (Linux; Android 14; %s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36"
+)
+
+// Devices are the built-in device presets in portrait orientation. Desktop
+// presets keep the browser's own user agent.
+var Devices = map[string]Device{
+	"iphone-se":         {Width: 375, Height: 667, ScaleFactor: 2, Mobile: true, Touch: true, UserAgent: iOSUserAgent},
+	"iphone-15":         {Width: 393, Height: 852, ScaleFactor: 3, Mobile: true, Touch: true, UserAgent: iOSUserAgent},
+	"iphone-15-pro-max": {Width: 430, Height: 932, ScaleFactor: 3, Mobile: true, Touch: true, UserAgent: iOSUserAgent},
+	"pixel-7":           {Width: 412, Height: 915, ScaleFactor: 2.625, Mobile: true, Touch: true, UserAgent: fmt.Sprintf(androidUserAgent, "Pixel 7")},
+	"pixel-8-pro":       {Width: 448, Height: 998, ScaleFactor: 2.25, Mobile: true, Touch: true, UserAgent: fmt.Sprintf(androidUserAgent, "Pixel 8 Pro")},
+	"galaxy-s23":        {Width: 360, Height: 780, ScaleFactor: 3, Mobile: true, Touch: true, UserAgent: fmt.Sprintf(androidUserAgent, "SM-S911B")},
+	"ipad-mini":         {Width: 768, Height: 1024, ScaleFactor: 2, Mobile: true, Touch: true, UserAgent: iPadUserAgent},
+	"ipad":              {Width: 820, Height: 1180, ScaleFactor: 2, Mobile: true, Touch: true, UserAgent: iPadUserAgent},
+	"ipad-pro":          {Width: 1024, Height: 1366, ScaleFactor: 2, Mobile: true, Touch: true, UserAgent: iPadUserAgent},
+	"laptop":            {Width: 1366, Height: 768, ScaleFactor: 1},
+	"laptop-hidpi":      {Width: 1440, Height: 900, ScaleFactor: 2},
+	"desktop":           {Width: 1920, Height: 1080, ScaleFactor: 1},
+	"desktop-1440p":     {Width: 2560, Height: 1440, ScaleFactor: 1},
+}
+
+// DeviceNames returns the preset names in order, followed by DeviceDefault
+func DeviceNames() []string {
+	names := make([]string, 0, len(Devices)+1)
+	for name := range Devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, DeviceDefault)
+}
+
+// localePattern accepts BCP 47 style tags such as en, de-DE or zh-Hant-TW
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// Geolocation is a position reported to the page, in degrees and meters
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty" description:"Meters, 100 when omitted"`
+}
+
+// ParseGeolocation parses "latitude,longitude[,accuracy]"
+func ParseGeolocation(s string) (*Geolocation, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, fmt.Errorf("invalid geolocation %q: use latitude,longitude[,accuracy]", s)
+	}
+	var values [3]float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid geolocation %q: use latitude,longitude[,accuracy]", s)
+		}
+		values[i] = v
+	}
+	return &Geolocation{Latitude: values[0], Longitude: values[1], Accuracy: values[2]}, nil
+}
+
+// acceptLanguage returns the Accept-Language header for a locale, falling
+// back to its language, e.g. "de-DE,de;q=0.9"
+func acceptLanguage(locale string) string {
+	if locale == "" {
+		return ""
+	}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		return locale + "," + lang + ";q=0.9"
+	}
+	return locale
+}
+
+// Emulation is the screen, user agent, media and region state applied to
+// every page. A zero Width and Height keep the window size, a zero
+// ScaleFactor keeps the real one and empty strings keep the browser's own
+// settings.
+type Emulation struct {
+	Device        string       `json:"device,omitempty"`
+	Width         int          `json:"width"`
+	Height        int          `json:"height"`
+	ScaleFactor   float64      `json:"device_scale_factor"`
+	Mobile        bool         `json:"mobile"`
+	Touch         bool         `json:"touch"`
+	UserAgent     string       `json:"user_agent,omitempty"`
+	ColorScheme   string       `json:"color_scheme,omitempty"`
+	ReducedMotion bool         `json:"reduced_motion"`
+	Geolocation   *Geolocation `json:"geolocation,omitempty"`
+	Timezone      string       `json:"timezone,omitempty"`
+	Locale        string       `json:"locale,omitempty"`
+}
+
+func (e Emulation) String() string {
+	var b strings.Builder
+	if e.Device != "" {
+		fmt.Fprintf(&b, "Device: %s\n", e.Device)
+	}
+	if e.Width == 0 {
+		b.WriteString("Viewport: window size")
+	} else {
+		fmt.Fprintf(&b, "Viewport: %dx%d", e.Width, e.Height)
+	}
+	if e.ScaleFactor > 0 {
+		fmt.Fprintf(&b, " @%gx", e.ScaleFactor)
+	}
+	if e.Mobile {
+		b.WriteString(", mobile")
+	}
+	if e.Touch {
+		b.WriteString(", touch")
+	}
+	if e.UserAgent != "" {
+		fmt.Fprintf(&b, "\nUser agent: %s", e.UserAgent)
+	}
+	if e.ColorScheme != "" {
+		fmt.Fprintf(&b, "\nColor scheme: %s", e.ColorScheme)
+	}
+	fmt.Fprintf(&b, "\nReduced motion: %v", e.ReducedMotion)
+	if e.Geolocation != nil {
+		fmt.Fprintf(&b, "\nGeolocation: %g,%g", e.Geolocation.Latitude, e.Geolocation.Longitude)
+	}
+	if e.Timezone != "" {
+		fmt.Fprintf(&b, "\nTimezone: %s", e.Timezone)
+	}
+	if e.Locale != "" {
+		fmt.Fprintf(&b, "\nLocale: %s", e.Locale)
+	}
+	return b.String()
+}
+
+// Validate rejects settings Chrome cannot emulate
+func (e Emulation) Validate() error {
+	keepWindow := e.Width == 0 && e.Height == 0
+	if !keepWindow && (e.Width < 1 || e.Height < 1 || e.Width > maxViewportSize || e.Height > maxViewportSize) {
+		return fmt.Errorf("viewport must be between 1 and %d pixels in each dimension, got %dx%d", maxViewportSize, e.Width, e.Height)
+	}
+	if keepWindow && (e.Mobile || e.ScaleFactor > 0) {
+		return fmt.Errorf("mobile and device_scale_factor need a width and height")
+	}
+	if e.ScaleFactor < 0 {
+		return fmt.Errorf("device_scale_factor must not be negative")
+	}
+	switch e.ColorScheme {
+	case "", ColorSchemeLight, ColorSchemeDark:
+	default:
+		return fmt.Errorf("unsupported color scheme %q: use light or dark", e.ColorScheme)
+	}
+	if g := e.Geolocation; g != nil {
+		if g.Latitude < -90 || g.Latitude > 90 || g.Longitude < -180 || g.Longitude > 180 {
+			return fmt.Errorf("geolocation must have latitude between -90 and 90 and longitude between -180 and 180")
+		}
+		if g.Accuracy < 0 {
+			return fmt.Errorf("geolocation accuracy must not be negative")
+		}
+	}
+	if e.Locale != "" && !localePattern.MatchString(e.Locale) {
+		return fmt.Errorf("invalid locale %q: use a language tag such as en-US", e.Locale)
+	}
+	return nil
+}
+
+// EmulationUpdate changes part of an Emulation. Device replaces the screen
+// and user agent with a preset, rotated when Landscape is set; the other
+// fields override single settings and nil ones are kept.
+type EmulationUpdate struct {
+	Device        string   `json:"device,omitempty"`
+	Landscape     bool     `json:"landscape,omitempty"`
+	Width         *int     `json:"width,omitempty"`
+	Height        *int     `json:"height,omitempty"`
+	ScaleFactor   *float64 `json:"device_scale_factor,omitempty"`
+	Mobile        *bool    `json:"mobile,omitempty"`
+	Touch         *bool    `json:"touch,omitempty"`
+	UserAgent     *string  `json:"user_agent,omitempty"`
+	ColorScheme   *string  `json:"color_scheme,omitempty"`
+	ReducedMotion *bool    `json:"reduced_motion,omitempty"`
+
+	Geolocation      *Geolocation `json:"geolocation,omitempty"`
+	ClearGeolocation bool         `json:"clear_geolocation,omitempty"`
+	Timezone         *string      `json:"timezone,omitempty"`
+	Locale           *string      `json:"locale,omitempty"`
+}
+
+// applyUpdate returns e changed by u. Must be called with m.mu held.
+func (m *Manager) applyUpdate(e Emulation, u EmulationUpdate) (Emulation, error) {
+	switch u.Device {
+	case "":
+	case DeviceDefault:
+		e.Device = DeviceDefault
+		e.Width, e.Height = m.config.ViewportWidth, m.config.ViewportHeight
+		if m.remote != nil {
+			e.Width, e.Height = 0, 0
+		}
+		e.ScaleFactor, e.Mobile, e.Touch = 0, false, false
+		e.UserAgent = m.config.UserAgent
+	default:
+		d, ok := Devices[strings.ToLower(u.Device)]
+		if !ok {
+			return e, fmt.Errorf("unknown device %q: use one of %s", u.Device, strings.Join(DeviceNames(), ", "))
+		}
+		if u.Landscape {
+			d.Width, d.Height = d.Height, d.Width
+		}
+		e.Device = strings.ToLower(u.Device)
+		e.Width, e.Height = d.Width, d.Height
+		e.ScaleFactor, e.Mobile, e.Touch = d.ScaleFactor, d.Mobile, d.Touch
+		e.UserAgent = d.UserAgent
+	}
+
+	if u.Width != nil {
+		e.Width = *u.Width
+	}
+	if u.Height != nil {
+		e.Height = *u.Height
+	}
+	if u.ScaleFactor != nil {
+		e.ScaleFactor = *u.ScaleFactor
+	}
+	if u.Mobile != nil {
+		e.Mobile = *u.Mobile
+	}
+	if u.Touch != nil {
+		e.Touch = *u.Touch
+	}
+	if u.UserAgent != nil {
+		e.UserAgent = *u.UserAgent
+	}
+	if u.ColorScheme != nil {
+		e.ColorScheme = *u.ColorScheme
+	}
+	if u.ReducedMotion != nil {
+		e.ReducedMotion = *u.ReducedMotion
+	}
+	if u.ClearGeolocation {
+		e.Geolocation = nil
+	}
+	if u.Geolocation != nil {
+		g := *u.Geolocation
+		e.Geolocation = &g
+	}
+	if u.Timezone != nil {
+		e.Timezone = *u.Timezone
+	}
+	if u.Locale != nil {
+		e.Locale = strings.ReplaceAll(*u.Locale, "_", "-")
+	}
+	return e, e.Validate()
+}
+
+// Emulation returns the emulation applied to the page
+func (m *Manager) Emulation() Emulation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.emulation
+}
+
+// Emulate applies u on top of the current emulation and returns the result
+func (m *Manager) Emulate(u EmulationUpdate) (Emulation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.page == nil {
+		return m.emulation, fmt.Errorf("browser is not open")
+	}
+
+	e, err := m.applyUpdate(m.emulation, u)
+	if err != nil {
+		return m.emulation, err
+	}
+	if err := m.applyEmulation(e); err != nil {
+		return m.emulation, err
+	}
+	m.emulation = e
+	return e, nil
+}
+
+// applyEmulation sets the viewport, touch support, user agent, media
+// features, locale, timezone and geolocation of the page.
+// Must be called with m.mu held.
+func (m *Manager) applyEmulation(e Emulation) error {
+	orientation := &proto.EmulationScreenOrientation{Type: proto.EmulationScreenOrientationTypePortraitPrimary}
+	if e.Width > e.Height {
+		orientation = &proto.EmulationScreenOrientation{Type: proto.EmulationScreenOrientationTypeLandscapePrimary, Angle: 90}
+	}
+	// SetViewport is remembered by full page screenshots, which restore it
+	var metrics *proto.EmulationSetDeviceMetricsOverride
+	if e.Width > 0 {
+		metrics = &proto.EmulationSetDeviceMetricsOverride{
+			Width:             e.Width,
+			Height:            e.Height,
+			DeviceScaleFactor: e.ScaleFactor,
+			Mobile:            e.Mobile,
+			ScreenOrientation: orientation,
+		}
+	}
+	err := m.page.SetViewport(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to set viewport: %w", err)
+	}
+
+	touch := proto.EmulationSetTouchEmulationEnabled{Enabled: e.Touch}
+	if e.Touch {
+		points := 5
+		touch.MaxTouchPoints = &points
+	}
+	if err := touch.Call(m.page); err != nil {
+		return fmt.Errorf("failed to set touch emulation: %w", err)
+	}
+
+	userAgent := e.UserAgent
+	if userAgent == "" {
+		version, err := proto.BrowserGetVersion{}.Call(m.browser)
+		if err != nil {
+			return err
+		}
+		userAgent = version.UserAgent
+	}
+	err = proto.EmulationSetUserAgentOverride{
+		UserAgent:      userAgent,
+		AcceptLanguage: acceptLanguage(e.Locale),
+	}.Call(m.page)
+	if err != nil {
+		return fmt.Errorf("failed to set user agent: %w", err)
+	}
+	if err := (proto.EmulationSetLocaleOverride{Locale: e.Locale}).Call(m.page); err != nil {
+		return fmt.Errorf("failed to set locale: %w", err)
+	}
+	if err := (proto.EmulationSetTimezoneOverride{TimezoneID: e.Timezone}).Call(m.page); err != nil {
+		return fmt.Errorf("failed to set timezone %q: %w", e.Timezone, err)
+	}
+
+	if g := e.Geolocation; g != nil {
+		// Pages only see the position once they are allowed to ask for it
+		err = proto.BrowserGrantPermissions{Permissions: []proto.BrowserPermissionType{proto.BrowserPermissionTypeGeolocation}}.Call(m.browser)
+		if err != nil {
+			return fmt.Errorf("failed to grant geolocation permission: %w", err)
+		}
+		accuracy := g.Accuracy
+		if accuracy == 0 {
+			accuracy = 100
+		}
+		err = proto.EmulationSetGeolocationOverride{Latitude: &g.Latitude, Longitude: &g.Longitude, Accuracy: &accuracy}.Call(m.page)
+	} else {
+		err = proto.EmulationClearGeolocationOverride{}.Call(m.page)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set geolocation: %w", err)
+	}
+
+	// Empty values restore the real media features
+	motion := ""
+	if e.ReducedMotion {
+		motion = "reduce"
+	}
+	err = proto.EmulationSetEmulatedMedia{Features: []*proto.EmulationMediaFeature{
+		{Name: "prefers-color-scheme", Value: e.ColorScheme},
+		{Name: "prefers-reduced-motion", Value: motion},
+	}}.Call(m.page)
+	if err != nil {
+		return fmt.Errorf("failed to set media emulation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reasons an ElementError can report
+const (
+	ReasonInvalidSelector = "invalid_selector"
+	ReasonNotFound        = "not_found"
+	ReasonAmbiguous       = "ambiguous"
+	ReasonHidden          = "hidden"
+	ReasonDisabled        = "disabled"
+	ReasonCovered         = "covered"
+	ReasonOutsideViewport = "outside_viewport"
+	ReasonDetached        = "detached"
+)
+
+// Candidate describes an element the caller may have meant
+type Candidate struct {
+	Selector string `json:"selector"`
+	Role     string `json:"role,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+func (c Candidate) String() string {
+	s := c.Selector
+	if c.Role != "" {
+		s += " [" + c.Role + "]"
+	}
+	if c.Text != "" {
+		s += fmt.Sprintf(" %q", c.Text)
+	}
+	return s
+}
+
+// ElementError explains why a selector could not be resolved to a usable element
+type ElementError struct {
+	Selector   string      `json:"selector"`
+	Reason     string      `json:"reason" enum:"invalid_selector,not_found,ambiguous,hidden,disabled,covered,outside_viewport,detached"`
+	Matches    int         `json:"matches" description:"Number of elements the selector matched"`
+	Detail     string      `json:"detail,omitempty"`
+	Candidates []Candidate `json:"candidates,omitempty" description:"Matching elements when ambiguous, otherwise similar elements"`
+}
+
+func (e *ElementError) Error() string {
+	var msg string
+	switch e.Reason {
+	case ReasonInvalidSelector:
+		msg = fmt.Sprintf("invalid selector %q: %s", e.Selector, e.Detail)
+	case ReasonNotFound:
+		msg = fmt.Sprintf("element not found: %s", e.Selector)
+		if e.Detail != "" {
+			msg += " (" + e.Detail + ")"
+		}
+	case ReasonAmbiguous:
+		msg = fmt.Sprintf("selector %q is ambiguous: it matched %d elements, make it more specific", e.Selector, e.Matches)
+	case ReasonCovered:
+		msg = fmt.Sprintf("element %q is covered by another element: %s", e.Selector, e.Detail)
+	default:
+		msg = fmt.Sprintf("element %q is %s", e.Selector, strings.ReplaceAll(e.Reason, "_", " "))
+		if e.Detail != "" {
+			msg += ": " + e.Detail
+		}
+	}
+
+	if len(e.Candidates) == 0 {
+		return msg
+	}
+
+	var b strings.Builder
+	b.WriteString(msg)
+	if e.Reason == ReasonAmbiguous {
+		b.WriteString("\nMatching elements:")
+	} else {
+		b.WriteString("\nSimilar elements:")
+	}
+	for _, c := range e.Candidates {
+		b.WriteString("\n  - " + c.String())
+	}
+	return b.String()
+}
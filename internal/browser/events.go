@@ -0,0 +1,164 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Page events passed to the functions added with OnPageEvent
+const (
+	EventLoaded     = "loaded"     // the page loaded or navigated within the document
+	EventDownloaded = "downloaded" // a download finished
+)
+
+// PageEvent is something that changed the page or the downloads
+type PageEvent struct {
+	Type string
+	URL  string // the page URL, for loaded
+	Name string // the file name in the downloads directory, for downloaded
+}
+
+// listeners are called on page events. They have their own lock as events
+// arrive on the watchers' goroutines while the Manager may be busy.
+type listeners struct {
+	mu  sync.Mutex
+	fns []func(PageEvent)
+}
+
+func (l *listeners) add(fn func(PageEvent)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fns = append(l.fns, fn)
+}
+
+func (l *listeners) emit(e PageEvent) {
+	l.mu.Lock()
+	fns := l.fns
+	l.mu.Unlock()
+	for _, fn := range fns {
+		fn(e)
+	}
+}
+
+// OnPageEvent calls fn whenever the page loads or navigates within the
+// document and whenever a download finishes, in every browser session. fn
+// runs on an event goroutine and must not block.
+func (m *Manager) OnPageEvent(fn func(PageEvent)) {
+	m.listeners.add(fn)
+}
+
+// watchLoads reports the loads and same-document navigations of the top
+// frame of page
+func (m *Manager) watchLoads(page *rod.Page) {
+	var url string
+	if info, err := page.Info(); err == nil {
+		url = info.URL
+	}
+
+	wait := page.EachEvent(
+		func(e *proto.PageFrameNavigated) {
+			if e.Frame.ParentID == "" {
+				url = e.Frame.URL
+			}
+		},
+		func(e *proto.PageLoadEventFired) {
+			m.listeners.emit(PageEvent{Type: EventLoaded, URL: url})
+		},
+		func(e *proto.PageNavigatedWithinDocument) {
+			if e.FrameID == page.FrameID {
+				url = e.URL
+				m.listeners.emit(PageEvent{Type: EventLoaded, URL: url})
+			}
+		},
+	)
+	go wait()
+}
+
+// DownloadsDir returns where files the page downloads are saved
+func (m *Manager) DownloadsDir() string {
+	return filepath.Join(m.config.OutputDir, "downloads")
+}
+
+// ReadDownload returns a file from the downloads directory
+func (m *Manager) ReadDownload(name string) ([]byte, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid download name %q", name)
+	}
+	return os.ReadFile(filepath.Join(m.DownloadsDir(), name))
+}
+
+// saveDownloads saves the downloads of a launched browser to the downloads
+// directory under their suggested names. An attached browser keeps saving
+// where its user chose. Must be called with m.mu held.
+func (m *Manager) saveDownloads() error {
+	if m.remote != nil && m.browserContext == "" {
+		return nil
+	}
+	dir, err := filepath.Abs(m.DownloadsDir())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create downloads directory: %w", err)
+	}
+
+	// Files are saved under their GUID and renamed when complete, so a
+	// partial download never shows under its real name. Events are handled
+	// one at a time.
+	names := map[string]string{}
+	wait := m.browser.EachEvent(
+		func(e *proto.BrowserDownloadWillBegin) {
+			names[e.GUID] = e.SuggestedFilename
+		},
+		func(e *proto.BrowserDownloadProgress) {
+			if e.State == proto.BrowserDownloadProgressStateInProgress {
+				return
+			}
+			suggested := names[e.GUID]
+			delete(names, e.GUID)
+
+			partial := filepath.Join(dir, e.GUID)
+			if e.State != proto.BrowserDownloadProgressStateCompleted {
+				_ = os.Remove(partial)
+				return
+			}
+			name := freeName(dir, suggested)
+			if err := os.Rename(partial, filepath.Join(dir, name)); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save download %s: %v\n", name, err)
+				return
+			}
+			m.listeners.emit(PageEvent{Type: EventDownloaded, Name: name})
+		},
+	)
+	go wait()
+
+	return proto.BrowserSetDownloadBehavior{
+		Behavior:         proto.BrowserSetDownloadBehaviorBehaviorAllowAndName,
+		BrowserContextID: m.browserContext,
+		DownloadPath:     dir,
+		EventsEnabled:    true,
+	}.Call(m.browser)
+}
+
+// freeName returns name, or name with a number added, that is not yet
+// taken in dir
+func freeName(dir, name string) string {
+	name = strings.TrimLeft(filepath.Base(name), ".")
+	if name == "" {
+		name = "download"
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			return name
+		}
+		name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+}
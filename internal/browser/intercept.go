@@ -0,0 +1,98 @@
+package browser
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// interceptor pauses requests of one page to hold its documents to the URL
+// policy and answer proxy authentication. A session gets a single Fetch
+// setup, so both share it. A nil interceptor blocks nothing. Popups and tabs
+// the page opens, and frames nested in a cross-site frame, are other
+// targets that it doesn't see.
+type interceptor struct {
+	mu      sync.Mutex
+	blocked *PolicyError
+}
+
+// reset forgets navigations blocked before an action
+func (i *interceptor) reset() {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.blocked = nil
+}
+
+// violation returns the navigation blocked since the last reset, if any
+func (i *interceptor) violation() error {
+	if i == nil {
+		return nil
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.blocked == nil {
+		return nil
+	}
+	return i.blocked
+}
+
+func (i *interceptor) block(err *PolicyError) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.blocked = err
+}
+
+// intercept checks the documents page loads, in the main frame and in
+// frames, against policy and answers proxy authentication challenges with
+// the credentials of proxy. Only documents are paused unless there are
+// credentials to answer with. A blocked frame only fails to load; a blocked
+// main frame navigation is also reported by violation.
+func intercept(page *rod.Page, policy *Policy, proxy Proxy) (*interceptor, error) {
+	i := &interceptor{}
+	auth := proxy.Username != ""
+
+	wait := page.EachEvent(
+		func(e *proto.FetchRequestPaused) {
+			if e.ResourceType == proto.NetworkResourceTypeDocument {
+				if err := policy.violation(e.Request.URL); err != nil {
+					if e.FrameID == page.FrameID {
+						i.block(err)
+					}
+					_ = proto.FetchFailRequest{RequestID: e.RequestID, ErrorReason: proto.NetworkErrorReasonBlockedByClient}.Call(page)
+					return
+				}
+			}
+			_ = proto.FetchContinueRequest{RequestID: e.RequestID}.Call(page)
+		},
+		func(e *proto.FetchAuthRequired) {
+			res := &proto.FetchAuthChallengeResponse{Response: proto.FetchAuthChallengeResponseResponseDefault}
+			if e.AuthChallenge.Source == proto.FetchAuthChallengeSourceProxy {
+				res = &proto.FetchAuthChallengeResponse{
+					Response: proto.FetchAuthChallengeResponseResponseProvideCredentials,
+					Username: proxy.Username,
+					Password: proxy.Password,
+				}
+			}
+			_ = proto.FetchContinueWithAuth{RequestID: e.RequestID, AuthChallengeResponse: res}.Call(page)
+		},
+	)
+	go wait()
+
+	pattern := &proto.FetchRequestPattern{URLPattern: "*"}
+	if !auth {
+		pattern.ResourceType = proto.NetworkResourceTypeDocument
+	}
+	err := proto.FetchEnable{
+		Patterns:           []*proto.FetchRequestPattern{pattern},
+		HandleAuthRequests: auth,
+	}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable request interception: %w", err)
+	}
+	return i, nil
+}
@@ -0,0 +1,104 @@
+package browser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Locator engines. A selector segment may start with "<engine>=" to pick one;
+// segments without a prefix are CSS, or XPath when they start with "/".
+const (
+	EngineCSS         = "css"
+	EngineXPath       = "xpath"
+	EngineText        = "text"
+	EngineRole        = "role"
+	EngineLabel       = "label"
+	EnginePlaceholder = "placeholder"
+	EngineRef         = "ref"
+)
+
+// LocatorHelp describes the selector syntax for tool and API documentation
+const LocatorHelp = `CSS selector, or a locator: text=Sign in, role=button[name="Submit"], label=Email, placeholder=Search, xpath=//a, or ref=12 from an annotated screenshot. Quoted values match exactly, unquoted values match a substring. Use 'host >>> inner' to reach inside shadow roots`
+
+var engines = map[string]bool{
+	EngineCSS:         true,
+	EngineXPath:       true,
+	EngineText:        true,
+	EngineRole:        true,
+	EngineLabel:       true,
+	EnginePlaceholder: true,
+	EngineRef:         true,
+}
+
+var (
+	enginePrefix = regexp.MustCompile(`^([a-z]+)=`)
+	roleSyntax   = regexp.MustCompile(`^([a-z]+)\s*(?:\[\s*name\s*=\s*(.*?)\s*\])?$`)
+)
+
+// locatorPart is one ">>>" segment of a selector, as passed to queryAllJS
+type locatorPart struct {
+	Engine string `json:"engine"`
+	Value  string `json:"value"`
+	Name   string `json:"name,omitempty"`
+	Exact  bool   `json:"exact"`
+}
+
+// semantic reports whether the part matches by meaning rather than structure.
+// Semantic locators must resolve to a single element for single-element actions.
+func (p locatorPart) semantic() bool {
+	switch p.Engine {
+	case EngineText, EngineRole, EngineLabel, EnginePlaceholder:
+		return true
+	}
+	return false
+}
+
+// parseLocator splits a selector into its shadow-piercing segments and
+// resolves the engine of each one
+func parseLocator(selector string) ([]locatorPart, error) {
+	var parts []locatorPart
+	for _, segment := range strings.Split(selector, ShadowPierce) {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			return nil, invalidSelector(selector, "empty segment")
+		}
+
+		part := locatorPart{Engine: EngineCSS, Value: segment}
+		if m := enginePrefix.FindStringSubmatch(segment); m != nil && engines[m[1]] {
+			part.Engine = m[1]
+			part.Value = strings.TrimSpace(segment[len(m[0]):])
+		} else if strings.HasPrefix(segment, "/") {
+			part.Engine = EngineXPath
+		}
+		if part.Value == "" {
+			return nil, invalidSelector(selector, part.Engine+"= needs a value")
+		}
+
+		switch part.Engine {
+		case EngineText, EngineLabel, EnginePlaceholder:
+			part.Value, part.Exact = unquote(part.Value)
+		case EngineRole:
+			m := roleSyntax.FindStringSubmatch(part.Value)
+			if m == nil {
+				return nil, invalidSelector(selector, `expected role=<role> or role=<role>[name="<name>"]`)
+			}
+			part.Value = m[1]
+			part.Name, part.Exact = unquote(m[2])
+		}
+
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+func invalidSelector(selector, detail string) error {
+	return &ElementError{Selector: selector, Reason: ReasonInvalidSelector, Detail: detail}
+}
+
+// unquote strips matching quotes, reporting whether the value was quoted
+func unquote(s string) (string, bool) {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1], true
+	}
+	return s, false
+}
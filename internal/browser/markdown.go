@@ -0,0 +1,76 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// markdownJS renders the visible content of the page as Markdown: headings,
+// paragraphs, links, lists, tables, code and images
+const markdownJS = `() => {
+	const skip = new Set(['SCRIPT', 'STYLE', 'NOSCRIPT', 'TEMPLATE', 'SVG', 'CANVAS', 'IFRAME', 'HEAD']);
+	const block = new Set(['P', 'DIV', 'SECTION', 'ARTICLE', 'MAIN', 'HEADER', 'FOOTER', 'NAV', 'ASIDE',
+		'FORM', 'FIELDSET', 'FIGURE', 'FIGCAPTION', 'BLOCKQUOTE', 'DL', 'DT', 'DD', 'ADDRESS']);
+	const hidden = el => !el.checkVisibility({ opacityProperty: true, visibilityProperty: true });
+	const clean = s => s.replace(/\s+/g, ' ');
+	const inline = el => [...el.childNodes].map(node).join('').trim();
+
+	const cell = el => inline(el).replace(/\|/g, '\\|').replace(/\n+/g, ' ');
+	const table = el => {
+		const rows = [...el.rows].map(r => [...r.cells].map(cell));
+		if (!rows.length) return '';
+		const width = Math.max(...rows.map(r => r.length));
+		const line = r => '| ' + [...r, ...Array(width - r.length).fill('')].join(' | ') + ' |';
+		return '\n\n' + [line(rows[0]), line(Array(width).fill('---')), ...rows.slice(1).map(line)].join('\n') + '\n\n';
+	};
+	const list = (el, depth) => '\n' + [...el.children].filter(li => li.tagName === 'LI' && !hidden(li)).map((li, i) => {
+		const bullet = el.tagName === 'OL' ? (i + 1) + '. ' : '- ';
+		const nested = [...li.children].filter(c => c.tagName === 'UL' || c.tagName === 'OL');
+		const own = [...li.childNodes].filter(c => !nested.includes(c)).map(node).join('').trim();
+		return '  '.repeat(depth) + bullet + own + nested.map(n => list(n, depth + 1)).join('');
+	}).join('\n') + (depth ? '' : '\n\n');
+
+	const node = n => {
+		if (n.nodeType === Node.TEXT_NODE) return clean(n.textContent);
+		if (n.nodeType !== Node.ELEMENT_NODE || skip.has(n.tagName.toUpperCase()) || hidden(n)) return '';
+		const tag = n.tagName;
+		switch (tag) {
+		case 'H1': case 'H2': case 'H3': case 'H4': case 'H5': case 'H6':
+			return '\n\n' + '#'.repeat(+tag[1]) + ' ' + inline(n) + '\n\n';
+		case 'BR': return '\n';
+		case 'HR': return '\n\n---\n\n';
+		case 'STRONG': case 'B': { const t = inline(n); return t ? '**' + t + '**' : ''; }
+		case 'EM': case 'I': { const t = inline(n); return t ? '_' + t + '_' : ''; }
+		case 'CODE': return '` + "`" + `' + n.textContent + '` + "`" + `';
+		case 'PRE': return '\n\n` + "```" + `\n' + n.innerText.replace(/\n$/, '') + '\n` + "```" + `\n\n';
+		case 'A': {
+			const t = inline(n);
+			return n.href && t && !n.href.startsWith('javascript:') ? '[' + t + '](' + n.href + ')' : t;
+		}
+		case 'IMG': return n.alt ? '![' + clean(n.alt) + '](' + n.src + ')' : '';
+		case 'UL': case 'OL': return list(n, 0);
+		case 'TABLE': return table(n);
+		case 'INPUT': case 'SELECT': case 'TEXTAREA': return '';
+		}
+		const text = [...n.childNodes].map(node).join('');
+		return block.has(tag) ? '\n\n' + text.trim() + '\n\n' : text;
+	};
+
+	const body = node(document.body).replace(/[ \t]+\n/g, '\n').replace(/\n{3,}/g, '\n\n').trim();
+	return document.title ? '# ' + document.title + '\n\n' + body : body;
+}`
+
+// Markdown returns the visible content of the current page as Markdown
+func (m *Manager) Markdown() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.page == nil {
+		return "", fmt.Errorf("browser is not open")
+	}
+	res, err := m.page.Timeout(m.config.BrowserTimeout).Eval(markdownJS)
+	if err != nil {
+		return "", fmt.Errorf("failed to render page as Markdown: %w", err)
+	}
+	return strings.TrimSpace(res.Value.Str()) + "\n", nil
+}
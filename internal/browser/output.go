@@ -0,0 +1,73 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OutputPath returns where a new generated file called name is stored. Only
+// the base name is used so callers can't write outside the output
+// directory. An empty name gets a timestamped one with the given extension.
+// Existing files are never replaced: when name is taken, a number is added,
+// as in report-2.pdf.
+func (m *Manager) OutputPath(name, ext string) string {
+	for n := 1; ; n++ {
+		path := m.numberedPath(name, ext, n)
+		if _, err := os.Lstat(path); err != nil {
+			return path
+		}
+	}
+}
+
+// SaveOutput writes data to a new file in the output directory, numbered
+// like OutputPath when name is taken, and returns the file path
+func (m *Manager) SaveOutput(name, ext string, data []byte) (string, error) {
+	if err := os.MkdirAll(m.config.OutputDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// O_EXCL keeps concurrent saves of the same name from replacing each
+	// other's file
+	for n := 1; ; n++ {
+		path := m.numberedPath(name, ext, n)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", path, err)
+		}
+
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		return path, nil
+	}
+}
+
+// numberedPath returns the path of output file name, with -n added before
+// the extension for every n after the first
+func (m *Manager) numberedPath(name, ext string, n int) string {
+	name = filepath.Base(name)
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "surfmate-" + time.Now().Format("20060102-150405")
+	}
+	if strings.HasSuffix(strings.ToLower(name), ext) {
+		name, ext = name[:len(name)-len(ext)], name[len(name)-len(ext):]
+	}
+	if n > 1 {
+		name += "-" + strconv.Itoa(n)
+	}
+	return filepath.Join(m.config.OutputDir, name+ext)
+}
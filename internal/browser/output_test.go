@@ -0,0 +1,58 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/config"
+)
+
+func TestSaveOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		file     string
+		ext      string
+		want     string
+	}{
+		{name: "new file", file: "report", ext: ".pdf", want: "report.pdf"},
+		{name: "extension kept", file: "report.PDF", ext: ".pdf", want: "report.PDF"},
+		{name: "taken", existing: []string{"report.pdf"}, file: "report", ext: ".pdf", want: "report-2.pdf"},
+		{name: "taken twice", existing: []string{"report.pdf", "report-2.pdf"}, file: "report.pdf", ext: ".pdf", want: "report-3.pdf"},
+		{name: "other extension not taken", existing: []string{"report.png"}, file: "report", ext: ".pdf", want: "report.pdf"},
+		{name: "directory stripped", file: "../../etc/report", ext: ".pdf", want: "report.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("old"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m := &Manager{config: &config.Config{OutputDir: dir}}
+
+			want := filepath.Join(dir, tt.want)
+			if got := m.OutputPath(tt.file, tt.ext); got != want {
+				t.Errorf("OutputPath() = %s, want %s", got, want)
+			}
+			got, err := m.SaveOutput(tt.file, tt.ext, []byte("new"))
+			if err != nil {
+				t.Fatalf("SaveOutput() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("SaveOutput() = %s, want %s", got, want)
+			}
+
+			for _, name := range tt.existing {
+				if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != "old" {
+					t.Errorf("%s was replaced", name)
+				}
+			}
+			if data, _ := os.ReadFile(got); string(data) != "new" {
+				t.Errorf("%s = %q, want the saved data", got, data)
+			}
+		})
+	}
+}
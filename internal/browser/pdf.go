@@ -0,0 +1,83 @@
+package browser
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// PaperSizes maps paper names to width and height in inches
+var PaperSizes = map[string][2]float64{
+	"letter":  {8.5, 11},
+	"legal":   {8.5, 14},
+	"tabloid": {11, 17},
+	"a3":      {11.69, 16.54},
+	"a4":      {8.27, 11.69},
+	"a5":      {5.83, 8.27},
+}
+
+var pageRangesSyntax = regexp.MustCompile(`^\s*\d+(\s*-\s*\d*)?(\s*,\s*\d+(\s*-\s*\d*)?)*\s*$`)
+
+// PDFOptions controls how the page is printed
+type PDFOptions struct {
+	Paper      string
+	Landscape  bool
+	Background bool
+	// Margin applies to every side, in inches. Nil keeps Chrome's default.
+	Margin     *float64
+	PageRanges string
+}
+
+// Validate fills in defaults and rejects unsupported values
+func (o *PDFOptions) Validate() error {
+	o.Paper = strings.ToLower(o.Paper)
+	if o.Paper == "" {
+		o.Paper = "letter"
+	}
+	if _, ok := PaperSizes[o.Paper]; !ok {
+		return fmt.Errorf("unsupported paper size %q: use letter, legal, tabloid, a3, a4 or a5", o.Paper)
+	}
+	if o.Margin != nil && *o.Margin < 0 {
+		return fmt.Errorf("margin must not be negative")
+	}
+	if o.PageRanges != "" && !pageRangesSyntax.MatchString(o.PageRanges) {
+		return fmt.Errorf("invalid page ranges %q: use e.g. 1-5, 8, 11-13", o.PageRanges)
+	}
+	return nil
+}
+
+// PDF prints the current page to PDF
+func (m *Manager) PDF(opts PDFOptions) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.page == nil {
+		return nil, fmt.Errorf("browser is not open")
+	}
+
+	size := PaperSizes[opts.Paper]
+	req := &proto.PagePrintToPDF{
+		Landscape:       opts.Landscape,
+		PrintBackground: opts.Background,
+		PaperWidth:      &size[0],
+		PaperHeight:     &size[1],
+		MarginTop:       opts.Margin,
+		MarginBottom:    opts.Margin,
+		MarginLeft:      opts.Margin,
+		MarginRight:     opts.Margin,
+		PageRanges:      opts.PageRanges,
+	}
+
+	r, err := m.page.Timeout(m.config.BrowserTimeout).PDF(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to print page (some Chrome versions only print to PDF with HEADLESS=true): %w", err)
+	}
+	return io.ReadAll(r)
+}
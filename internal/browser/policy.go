@@ -0,0 +1,201 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/config"
+)
+
+// PolicyError reports a URL the browser is not allowed to open
+type PolicyError struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s is blocked by the URL policy: %s", e.URL, e.Reason)
+}
+
+// Policy decides which URLs the browser may open. A URL matching a deny
+// rule is blocked. With allow rules, only matching URLs may be opened.
+// Schemes other than http, https, about and data, and private network
+// addresses, are blocked unless an allow rule matches them. Host names are
+// checked by the addresses they resolve to here; Chrome resolves them again,
+// so a name that changes its addresses in between still gets through.
+type Policy struct {
+	allow        []urlRule
+	deny         []urlRule
+	allowPrivate bool
+
+	// lookup resolves host names to check them for private addresses
+	lookup func(host string) []net.IP
+}
+
+// lookupTimeout bounds the DNS lookup of a host checked for private
+// addresses
+const lookupTimeout = 2 * time.Second
+
+// resolve returns the addresses of host, or none when it can't be resolved
+// in time
+func resolve(host string) []net.IP {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	ips, _ := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	return ips
+}
+
+// urlRule is one allow or deny rule
+type urlRule struct {
+	pattern string
+	match   func(u *url.URL, raw string) bool
+}
+
+// NewPolicy parses the allow and deny rules. A rule is a domain, also
+// matching its subdomains, an IP range such as 10.0.0.0/8, a host glob such
+// as *.example.com, a URL glob such as https://example.com/docs/* or a
+// regular expression on the URL prefixed with re:
+func NewPolicy(allow, deny []string, allowPrivate bool) (*Policy, error) {
+	p := &Policy{allowPrivate: allowPrivate, lookup: resolve}
+	for _, pattern := range allow {
+		r, err := parseRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		p.allow = append(p.allow, r)
+	}
+	for _, pattern := range deny {
+		r, err := parseRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		p.deny = append(p.deny, r)
+	}
+	return p, nil
+}
+
+// configPolicy returns the URL policy set in the configuration
+func configPolicy(cfg *config.Config) (*Policy, error) {
+	return NewPolicy(config.List(cfg.URLAllow), config.List(cfg.URLDeny), cfg.AllowPrivateNetwork)
+}
+
+func parseRule(pattern string) (urlRule, error) {
+	r := urlRule{pattern: pattern}
+
+	switch {
+	case strings.HasPrefix(pattern, "re:"):
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, "re:"))
+		if err != nil {
+			return r, fmt.Errorf("invalid URL rule %q: %w", pattern, err)
+		}
+		r.match = func(u *url.URL, raw string) bool { return re.MatchString(raw) }
+
+	case strings.Contains(pattern, "://"):
+		re := globRegexp(pattern)
+		r.match = func(u *url.URL, raw string) bool { return re.MatchString(raw) }
+
+	case strings.Contains(pattern, "*"):
+		re := globRegexp(strings.ToLower(pattern))
+		r.match = func(u *url.URL, raw string) bool { return re.MatchString(strings.ToLower(u.Hostname())) }
+
+	case strings.Contains(pattern, "/"):
+		_, network, err := net.ParseCIDR(pattern)
+		if err != nil {
+			return r, fmt.Errorf("invalid URL rule %q: %w", pattern, err)
+		}
+		r.match = func(u *url.URL, raw string) bool {
+			ip := net.ParseIP(u.Hostname())
+			return ip != nil && network.Contains(ip)
+		}
+
+	default:
+		domain := strings.ToLower(strings.TrimPrefix(pattern, "."))
+		r.match = func(u *url.URL, raw string) bool {
+			host := strings.ToLower(u.Hostname())
+			return host == domain || strings.HasSuffix(host, "."+domain)
+		}
+	}
+	return r, nil
+}
+
+// globRegexp matches s in full, with * matching any run of characters
+func globRegexp(s string) *regexp.Regexp {
+	return regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(s), `\*`, ".*") + "$")
+}
+
+// Check returns a *PolicyError when the browser may not open rawURL
+func (p *Policy) Check(rawURL string) error {
+	if err := p.violation(rawURL); err != nil {
+		return err
+	}
+	return nil
+}
+
+// violation returns why rawURL may not be opened, or nil if it may. A nil
+// policy belongs to a session that was never set up and blocks everything.
+func (p *Policy) violation(rawURL string) *PolicyError {
+	if rawURL == "about:blank" {
+		return nil
+	}
+	if p == nil {
+		return &PolicyError{URL: rawURL, Reason: "no URL policy is loaded"}
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return &PolicyError{URL: rawURL, Reason: "the URL is invalid"}
+	}
+
+	for _, r := range p.deny {
+		if r.match(u, rawURL) {
+			return &PolicyError{URL: rawURL, Reason: "it matches the deny rule " + r.pattern}
+		}
+	}
+	for _, r := range p.allow {
+		if r.match(u, rawURL) {
+			return nil
+		}
+	}
+	if len(p.allow) > 0 {
+		return &PolicyError{URL: rawURL, Reason: "it is not on the allow list"}
+	}
+
+	switch u.Scheme {
+	case "http", "https", "about", "data":
+	case "":
+		return &PolicyError{URL: rawURL, Reason: "the URL has no scheme such as https://"}
+	default:
+		return &PolicyError{URL: rawURL, Reason: u.Scheme + ": URLs are blocked unless allowed"}
+	}
+	if !p.allowPrivate && p.privateHost(u.Hostname()) {
+		return &PolicyError{URL: rawURL, Reason: "private network addresses are blocked unless allowed"}
+	}
+	return nil
+}
+
+// privateHost reports whether host is localhost or a private IP address,
+// or resolves to one
+func (p *Policy) privateHost(host string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return privateIP(ip)
+	}
+	if host == "" || p.lookup == nil {
+		return false
+	}
+	return slices.ContainsFunc(p.lookup(host), privateIP)
+}
+
+// privateIP reports whether ip is a loopback, private, link-local or
+// unspecified address
+func privateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+}
@@ -0,0 +1,105 @@
+package browser
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPolicyViolation(t *testing.T) {
+	hosts := map[string][]net.IP{
+		"example.com":       {net.ParseIP("93.184.215.14")},
+		"intranet.corp":     {net.ParseIP("10.1.2.3")},
+		"mixed.example.com": {net.ParseIP("93.184.215.14"), net.ParseIP("fd00::1")},
+	}
+
+	tests := []struct {
+		name         string
+		allow        []string
+		deny         []string
+		allowPrivate bool
+		url          string
+		blocked      bool
+	}{
+		// No rules
+		{name: "public https", url: "https://example.com/", blocked: false},
+		{name: "about:blank", url: "about:blank", blocked: false},
+		{name: "data URL", url: "data:text/html,hi", blocked: false},
+		{name: "file scheme", url: "file:///etc/passwd", blocked: true},
+		{name: "javascript scheme", url: "javascript:alert(1)", blocked: true},
+		{name: "no scheme", url: "example.com", blocked: true},
+
+		// Deny is checked before allow
+		{name: "deny wins over allow", allow: []string{"example.com"}, deny: []string{"login.example.com"}, url: "https://login.example.com/", blocked: true},
+		{name: "allow outside deny", allow: []string{"example.com"}, deny: []string{"login.example.com"}, url: "https://www.example.com/", blocked: false},
+		{name: "deny glob", deny: []string{"*.ads.net"}, url: "https://x.ads.net/", blocked: true},
+		{name: "deny regexp", deny: []string{"re:^https?://[^/]*pay"}, url: "https://paypal.com/", blocked: true},
+		{name: "not on allow list", allow: []string{"example.com"}, url: "https://other.com/", blocked: true},
+		{name: "allow URL glob", allow: []string{"https://example.com/docs/*"}, url: "https://example.com/docs/a", blocked: false},
+		{name: "outside allow URL glob", allow: []string{"https://example.com/docs/*"}, url: "https://example.com/admin", blocked: true},
+
+		// An allow rule overrides the scheme and private network checks
+		{name: "allow overrides scheme", allow: []string{"re:^file://"}, url: "file:///tmp/report.html", blocked: false},
+		{name: "allow overrides private", allow: []string{"localhost"}, url: "http://localhost:8080/", blocked: false},
+		{name: "allow IP range", allow: []string{"10.0.0.0/8"}, url: "http://10.1.2.3/", blocked: false},
+
+		// Literal private addresses
+		{name: "localhost", url: "http://localhost/", blocked: true},
+		{name: "localhost subdomain", url: "http://app.localhost/", blocked: true},
+		{name: "IPv4 loopback", url: "http://127.0.0.1/", blocked: true},
+		{name: "IPv4 10/8", url: "http://10.0.0.1/", blocked: true},
+		{name: "IPv4 172.16/12", url: "http://172.31.255.255/", blocked: true},
+		{name: "IPv4 outside 172.16/12", url: "http://172.32.0.1/", blocked: false},
+		{name: "IPv4 192.168/16", url: "http://192.168.1.1/", blocked: true},
+		{name: "IPv4 link-local", url: "http://169.254.169.254/", blocked: true},
+		{name: "IPv4 unspecified", url: "http://0.0.0.0/", blocked: true},
+		{name: "IPv4 public", url: "http://8.8.8.8/", blocked: false},
+		{name: "IPv6 loopback", url: "http://[::1]/", blocked: true},
+		{name: "IPv6 unique local", url: "http://[fd12:3456::1]:8080/", blocked: true},
+		{name: "IPv6 link-local", url: "http://[fe80::1]/", blocked: true},
+		{name: "IPv4-mapped IPv6", url: "http://[::ffff:192.168.0.1]/", blocked: true},
+		{name: "IPv6 public", url: "http://[2606:4700::1111]/", blocked: false},
+		{name: "private allowed", allowPrivate: true, url: "http://192.168.1.1/", blocked: false},
+
+		// Host names by the addresses they resolve to
+		{name: "name resolving to private", url: "https://intranet.corp/", blocked: true},
+		{name: "name with one private address", url: "https://mixed.example.com/", blocked: true},
+		{name: "unresolved name", url: "https://unknown.example/", blocked: false},
+		{name: "name resolving to private allowed", allowPrivate: true, url: "https://intranet.corp/", blocked: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPolicy(tt.allow, tt.deny, tt.allowPrivate)
+			if err != nil {
+				t.Fatalf("NewPolicy: %v", err)
+			}
+			p.lookup = func(host string) []net.IP { return hosts[host] }
+
+			err = p.Check(tt.url)
+			if blocked := err != nil; blocked != tt.blocked {
+				t.Errorf("Check(%q) = %v, want blocked %v", tt.url, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestPolicyNil(t *testing.T) {
+	var p *Policy
+	if err := p.Check("https://example.com/"); err == nil {
+		t.Error("nil policy allowed a URL")
+	}
+	if err := p.Check("about:blank"); err != nil {
+		t.Errorf("nil policy blocked about:blank: %v", err)
+	}
+}
+
+func TestNewPolicyInvalidRules(t *testing.T) {
+	for _, rule := range []string{"re:(", "10.0.0.0/33"} {
+		if _, err := NewPolicy([]string{rule}, nil, false); err == nil {
+			t.Errorf("NewPolicy accepted invalid allow rule %q", rule)
+		}
+		if _, err := NewPolicy(nil, []string{rule}, false); err == nil {
+			t.Errorf("NewPolicy accepted invalid deny rule %q", rule)
+		}
+	}
+}
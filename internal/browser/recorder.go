@@ -0,0 +1,263 @@
+package browser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Recording formats
+const (
+	RecordingGIF = "gif"
+	RecordingZip = "zip"
+)
+
+// Screencast settings. Frames are only sent when the page repaints, so a
+// quiet page costs nothing.
+const (
+	recordingQuality   = 60
+	recordingMaxWidth  = 1024
+	recordingMaxHeight = 768
+	// maxRecordingBytes bounds the memory the JPEG frames use; the oldest
+	// frames are dropped
+	maxRecordingBytes = 64 << 20
+	// maxGIFFrames bounds the frames decoded to build a GIF, as each takes
+	// about 800 KB decoded; evenly spaced frames are kept
+	maxGIFFrames = 150
+)
+
+type frame struct {
+	data []byte
+	at   time.Time
+}
+
+// Recorder captures screencast frames of a page
+type Recorder struct {
+	page   *rod.Page
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+	frames []frame
+	size   int // bytes of frame data held
+}
+
+// StartRecorder begins capturing screencast frames from page
+func StartRecorder(page *rod.Page) (*Recorder, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Recorder{
+		page:   page.Context(ctx),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	wait := r.page.EachEvent(func(e *proto.PageScreencastFrame) {
+		r.mu.Lock()
+		r.frames = append(r.frames, frame{data: e.Data, at: time.Now()})
+		r.size += len(e.Data)
+		for r.size > maxRecordingBytes && len(r.frames) > 1 {
+			r.size -= len(r.frames[0].data)
+			r.frames[0] = frame{}
+			r.frames = r.frames[1:]
+		}
+		r.mu.Unlock()
+
+		_ = proto.PageScreencastFrameAck{SessionID: e.SessionID}.Call(r.page)
+	})
+	go func() {
+		wait()
+		close(r.done)
+	}()
+
+	quality, maxWidth, maxHeight := recordingQuality, recordingMaxWidth, recordingMaxHeight
+	err := proto.PageStartScreencast{
+		Format:    proto.PageStartScreencastFormatJpeg,
+		Quality:   &quality,
+		MaxWidth:  &maxWidth,
+		MaxHeight: &maxHeight,
+	}.Call(r.page)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start screencast: %w", err)
+	}
+
+	return r, nil
+}
+
+// Stop ends the screencast and returns the number of frames captured
+func (r *Recorder) Stop() int {
+	_ = proto.PageStopScreencast{}.Call(r.page)
+	r.cancel()
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.frames)
+}
+
+// Encode assembles the captured frames in the given format
+func (r *Recorder) Encode(format string) ([]byte, error) {
+	r.mu.Lock()
+	frames := r.frames
+	r.mu.Unlock()
+
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames were recorded")
+	}
+
+	switch format {
+	case RecordingGIF:
+		return encodeGIF(frames)
+	case RecordingZip:
+		return encodeZip(frames)
+	}
+	return nil, fmt.Errorf("unsupported recording format %q: use gif or zip", format)
+}
+
+// encodeGIF builds an animated GIF that plays the frames at recorded speed,
+// keeping at most maxGIFFrames of them
+func encodeGIF(frames []frame) ([]byte, error) {
+	if len(frames) > maxGIFFrames {
+		kept := make([]frame, maxGIFFrames)
+		for i := range kept {
+			kept[i] = frames[i*len(frames)/maxGIFFrames]
+		}
+		frames = kept
+	}
+
+	anim := &gif.GIF{}
+	for i, f := range frames {
+		img, err := jpeg.Decode(bytes.NewReader(f.data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode frame %d: %w", i, err)
+		}
+
+		b := img.Bounds()
+		paletted := image.NewPaletted(b, palette.Plan9)
+		draw.Draw(paletted, b, img, b.Min, draw.Src)
+		anim.Image = append(anim.Image, paletted)
+
+		// GIF delays are in hundredths of a second; hold the last frame briefly
+		delay := 100
+		if i+1 < len(frames) {
+			delay = int(frames[i+1].at.Sub(f.at) / (10 * time.Millisecond))
+		}
+		anim.Delay = append(anim.Delay, max(delay, 2))
+
+		anim.Config.Width = max(anim.Config.Width, b.Dx())
+		anim.Config.Height = max(anim.Config.Height, b.Dy())
+	}
+	anim.Config.ColorModel = anim.Image[0].ColorModel()
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeZip stores the frames as numbered JPEGs with a frames.json index of
+// their offsets in milliseconds from the first frame
+func encodeZip(frames []frame) ([]byte, error) {
+	type entry struct {
+		File     string `json:"file"`
+		OffsetMS int64  `json:"offset_ms"`
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	index := make([]entry, 0, len(frames))
+	for i, f := range frames {
+		name := fmt.Sprintf("frame-%05d.jpg", i+1)
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return nil, err
+		}
+		index = append(index, entry{File: name, OffsetMS: f.at.Sub(frames[0].at).Milliseconds()})
+	}
+
+	w, err := zw.Create("frames.json")
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(w).Encode(index); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// StartRecording begins recording the current page
+func (m *Manager) StartRecording() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.page == nil {
+		return fmt.Errorf("browser is not open")
+	}
+
+	return m.startRecording()
+}
+
+// startRecording must be called with m.mu held
+func (m *Manager) startRecording() error {
+	if m.recorder != nil {
+		return fmt.Errorf("a recording is already in progress")
+	}
+
+	r, err := StartRecorder(m.page)
+	if err != nil {
+		return err
+	}
+	m.recorder = r
+	return nil
+}
+
+// StopRecording ends the recording and writes it to the output directory in
+// the given format (gif or zip), returning the file path and frame count
+func (m *Manager) StopRecording(format, name string) (string, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stopRecording(format, name)
+}
+
+// stopRecording must be called with m.mu held
+func (m *Manager) stopRecording(format, name string) (string, int, error) {
+	if m.recorder == nil {
+		return "", 0, fmt.Errorf("no recording is in progress")
+	}
+	if format == "" {
+		format = m.config.RecordingFormat
+	}
+	if format != RecordingGIF && format != RecordingZip {
+		return "", 0, fmt.Errorf("unsupported recording format %q: use gif or zip", format)
+	}
+
+	r := m.recorder
+	m.recorder = nil
+	frames := r.Stop()
+
+	data, err := r.Encode(format)
+	if err != nil {
+		return "", frames, err
+	}
+	path, err := m.SaveOutput(name, "."+format, data)
+	return path, frames, err
+}
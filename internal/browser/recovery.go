@@ -0,0 +1,163 @@
+package browser
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// BrowserDeadError reports that the browser crashed, was closed or
+// disconnected, and was not relaunched
+type BrowserDeadError struct {
+	Reason  string `json:"reason"`
+	LastURL string `json:"last_url,omitempty"`
+}
+
+func (e *BrowserDeadError) Error() string {
+	msg := fmt.Sprintf("the browser session ended: %s. Call open_browser to start a new one", e.Reason)
+	if e.LastURL != "" {
+		msg += fmt.Sprintf(" (last page: %s)", e.LastURL)
+	}
+	return msg
+}
+
+// health tracks whether one browser session is still usable. It has its own
+// lock so the watcher never waits on a Manager busy with the dead browser.
+// A nil health belongs to no session and is never dead.
+type health struct {
+	mu      sync.Mutex
+	dead    string
+	ended   bool
+	lastURL string
+}
+
+// reason returns why the session died, or "" while it is alive
+func (h *health) reason() string {
+	if h == nil {
+		return ""
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.dead
+}
+
+// url returns the last top-level URL the page navigated to
+func (h *health) url() string {
+	if h == nil {
+		return ""
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastURL
+}
+
+func (h *health) setURL(url string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastURL = url
+}
+
+// markDead records the first reason the session died, unless it was ended
+// on purpose
+func (h *health) markDead(reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.ended && h.dead == "" {
+		h.dead = reason
+	}
+}
+
+// end stops the session from being reported as dead when it is closed
+func (h *health) end() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ended = true
+}
+
+// watch follows page for crashes, closing and loss of the browser
+// connection, and remembers its URL
+func watch(page *rod.Page) *health {
+	h := &health{}
+	if info, err := page.Info(); err == nil {
+		h.lastURL = info.URL
+	}
+
+	wait := page.EachEvent(
+		func(e *proto.PageFrameNavigated) {
+			if e.Frame.ParentID == "" {
+				h.setURL(e.Frame.URL)
+			}
+		},
+		func(e *proto.InspectorTargetCrashed) {
+			h.markDead("the page crashed")
+		},
+		func(e *proto.InspectorDetached) {
+			h.markDead("the tab was closed (" + e.Reason + ")")
+		},
+	)
+	// The event stream only ends when the connection does
+	go func() {
+		wait()
+		h.markDead("the browser closed or disconnected")
+	}()
+	return h
+}
+
+// Recover checks whether the browser crashed or disconnected. If so, it is
+// relaunched (or reattached) with the same emulation and the last URL is
+// reopened when AutoRelaunch is configured or force is set, and a notice
+// saying so is returned; otherwise a *BrowserDeadError is returned.
+func (m *Manager) Recover(force bool) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reason := m.health.reason()
+	if m.browser == nil || reason == "" {
+		return "", nil
+	}
+	lastURL := m.health.url()
+	if !force && !m.config.AutoRelaunch {
+		return "", &BrowserDeadError{Reason: reason, LastURL: lastURL}
+	}
+
+	notice, err := m.restart(lastURL)
+	if err != nil {
+		return "", fmt.Errorf("the browser session ended (%s) and could not be restarted: %w", reason, err)
+	}
+	return fmt.Sprintf("The browser session ended (%s) and was restarted.", reason) + notice, nil
+}
+
+// restart replaces the browser session with a new one launched or attached
+// the same way, keeping its emulation, and reopens lastURL. It returns a
+// notice about reopening the page. Must be called with m.mu held.
+func (m *Manager) restart(lastURL string) (string, error) {
+	endpoint, emulation := m.endpoint, m.emulation
+	_ = m.teardown()
+
+	var err error
+	if endpoint != "" {
+		err = m.connect(endpoint)
+	} else {
+		err = m.launch()
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := m.applyEmulation(emulation); err == nil {
+		m.emulation = emulation
+	}
+
+	if lastURL == "" || lastURL == "about:blank" {
+		return "", nil
+	}
+	if err := m.navigate(lastURL); err != nil {
+		return fmt.Sprintf(" Reopening %s failed: %v.", lastURL, err), nil
+	}
+	_ = m.page.Timeout(m.config.BrowserTimeout).WaitLoad()
+	return " Reopened " + lastURL + ".", nil
+}
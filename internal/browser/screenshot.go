@@ -0,0 +1,164 @@
+package browser
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Screenshot formats
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+// DefaultScreenshotQuality is used for lossy formats when no quality is given
+const DefaultScreenshotQuality = 80
+
+// Rect is a region of the page in CSS pixels, relative to the document
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ScreenshotOptions controls what Screenshot captures and how it is encoded
+type ScreenshotOptions struct {
+	FullPage  bool
+	Selector  string
+	Clip      *Rect
+	Format    string
+	Quality   int
+	MaxWidth  int
+	MaxHeight int
+}
+
+// MIMEType returns the MIME type of images captured with these options
+func (o ScreenshotOptions) MIMEType() string {
+	switch o.Format {
+	case FormatJPEG, "jpg":
+		return "image/jpeg"
+	case FormatWebP:
+		return "image/webp"
+	}
+	return "image/png"
+}
+
+// Validate fills in defaults and rejects unsupported values
+func (o *ScreenshotOptions) Validate() error {
+	switch o.Format {
+	case "":
+		o.Format = FormatPNG
+	case "jpg":
+		o.Format = FormatJPEG
+	case FormatPNG, FormatJPEG, FormatWebP:
+	default:
+		return fmt.Errorf("unsupported screenshot format %q: use png, jpeg or webp", o.Format)
+	}
+
+	if o.Quality == 0 {
+		o.Quality = DefaultScreenshotQuality
+	}
+	if o.Quality < 1 || o.Quality > 100 {
+		return fmt.Errorf("screenshot quality must be between 1 and 100, got %d", o.Quality)
+	}
+	if o.MaxWidth < 0 || o.MaxHeight < 0 {
+		return fmt.Errorf("max_width and max_height must not be negative")
+	}
+	if o.Clip != nil && (o.Clip.Width <= 0 || o.Clip.Height <= 0) {
+		return fmt.Errorf("clip width and height must be positive")
+	}
+	return nil
+}
+
+// layout returns the visual viewport and the scrollable content size.
+// Must be called with m.mu held.
+func (m *Manager) layout() (*proto.PageVisualViewport, *proto.DOMRect, error) {
+	metrics, err := proto.PageGetLayoutMetrics{}.Call(m.page)
+	if err != nil {
+		return nil, nil, err
+	}
+	if metrics.CSSVisualViewport == nil || metrics.CSSContentSize == nil {
+		return nil, nil, fmt.Errorf("failed to get page layout metrics")
+	}
+	return metrics.CSSVisualViewport, metrics.CSSContentSize, nil
+}
+
+// screenshot captures the region described by opts, downscaled to fit
+// MaxWidth and MaxHeight. Must be called with m.mu held.
+func (m *Manager) screenshot(opts ScreenshotOptions) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	viewport, content, err := m.layout()
+	if err != nil {
+		return nil, err
+	}
+
+	var region Rect
+	resize := false
+	switch {
+	case opts.Selector != "":
+		el, err := m.element(opts.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if err := el.ScrollIntoView(); err != nil {
+			return nil, err
+		}
+		if viewport, _, err = m.layout(); err != nil {
+			return nil, err
+		}
+		shape, err := el.Shape()
+		if err != nil {
+			return nil, err
+		}
+		box := shape.Box()
+		region = Rect{X: box.X + viewport.PageX, Y: box.Y + viewport.PageY, Width: box.Width, Height: box.Height}
+	case opts.Clip != nil:
+		region = *opts.Clip
+	case opts.FullPage:
+		region = Rect{Width: content.Width, Height: content.Height}
+		resize = true
+	default:
+		region = Rect{X: viewport.PageX, Y: viewport.PageY, Width: viewport.ClientWidth, Height: viewport.ClientHeight}
+	}
+
+	// Limits apply to output pixels, which the device pixel ratio multiplies
+	dpr := 1.0
+	if res, err := m.page.Eval(`() => window.devicePixelRatio`); err == nil && res.Value.Num() > 0 {
+		dpr = res.Value.Num()
+	}
+	scale := 1.0
+	if opts.MaxWidth > 0 {
+		scale = math.Min(scale, float64(opts.MaxWidth)/(region.Width*dpr))
+	}
+	if opts.MaxHeight > 0 {
+		scale = math.Min(scale, float64(opts.MaxHeight)/(region.Height*dpr))
+	}
+
+	req := &proto.PageCaptureScreenshot{
+		Format: proto.PageCaptureScreenshotFormat(opts.Format),
+		Clip: &proto.PageViewport{
+			X:      region.X,
+			Y:      region.Y,
+			Width:  region.Width,
+			Height: region.Height,
+			Scale:  scale,
+		},
+	}
+	if opts.Format != FormatPNG {
+		req.Quality = &opts.Quality
+	}
+
+	// Full page captures resize the viewport to the content; other regions
+	// reaching outside the visible area are captured beyond the viewport
+	req.CaptureBeyondViewport = !resize && (region.X < viewport.PageX || region.Y < viewport.PageY ||
+		region.X+region.Width > viewport.PageX+viewport.ClientWidth ||
+		region.Y+region.Height > viewport.PageY+viewport.ClientHeight)
+	return m.page.Screenshot(resize, req)
+}
@@ -0,0 +1,318 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// ShadowPierce separates selector segments that cross into an open shadow root,
+// e.g. "my-app >>> login-form >>> input[name=email]".
+const ShadowPierce = ">>>"
+
+// locatorLibJS holds the helpers shared by the locator scripts: role and
+// accessible name computation, element descriptions and queryAll, which
+// resolves parsed locator parts to every matching element. Each part is
+// queried inside the shadow roots of the previous part's matches. When deep
+// is true every part also searches nested open shadow roots.
+const locatorLibJS = `
+	const norm = s => (s || '').replace(/\s+/g, ' ').trim().toLowerCase();
+	const matches = (actual, want, exact) => exact ? norm(actual) === norm(want) : norm(actual).includes(norm(want));
+	const skipped = new Set(['SCRIPT', 'STYLE', 'NOSCRIPT', 'TEMPLATE', 'HEAD', 'TITLE']);
+
+	const descendants = (root, deep) => {
+		const out = [...root.querySelectorAll('*')];
+		if (deep) {
+			for (const el of [...out]) {
+				if (el.shadowRoot) out.push(...descendants(el.shadowRoot, deep));
+			}
+		}
+		return out;
+	};
+
+	const textOf = (el, ids) => ids.split(/\s+/)
+		.map(id => el.getRootNode().getElementById(id))
+		.filter(Boolean)
+		.map(el => el.textContent)
+		.join(' ');
+
+	const roleOf = el => {
+		const explicit = el.getAttribute('role');
+		if (explicit) return explicit.trim().split(/\s+/)[0];
+		const tag = el.tagName.toLowerCase();
+		const type = (el.getAttribute('type') || 'text').toLowerCase();
+		switch (tag) {
+		case 'a': case 'area': return el.hasAttribute('href') ? 'link' : '';
+		case 'button': case 'summary': return 'button';
+		case 'input':
+			if (['button', 'submit', 'reset', 'image'].includes(type)) return 'button';
+			if (type === 'checkbox') return 'checkbox';
+			if (type === 'radio') return 'radio';
+			if (type === 'range') return 'slider';
+			if (type === 'number') return 'spinbutton';
+			if (type === 'search') return 'searchbox';
+			if (type === 'hidden') return '';
+			return 'textbox';
+		case 'textarea': return 'textbox';
+		case 'select': return el.multiple || el.size > 1 ? 'listbox' : 'combobox';
+		case 'option': return 'option';
+		case 'img': return el.getAttribute('alt') === '' ? 'presentation' : 'img';
+		case 'h1': case 'h2': case 'h3': case 'h4': case 'h5': case 'h6': return 'heading';
+		case 'ul': case 'ol': return 'list';
+		case 'li': return 'listitem';
+		case 'nav': return 'navigation';
+		case 'main': return 'main';
+		case 'form': return 'form';
+		case 'dialog': return 'dialog';
+		case 'table': return 'table';
+		case 'tr': return 'row';
+		case 'td': return 'cell';
+		case 'th': return 'columnheader';
+		}
+		return el.isContentEditable ? 'textbox' : '';
+	};
+
+	const labelOf = el => {
+		if (el.hasAttribute('aria-labelledby')) return textOf(el, el.getAttribute('aria-labelledby'));
+		if (el.hasAttribute('aria-label')) return el.getAttribute('aria-label');
+		if (el.labels && el.labels.length) return [...el.labels].map(l => l.textContent).join(' ');
+		return '';
+	};
+
+	const nameOf = el => {
+		const label = labelOf(el);
+		if (label) return label;
+		const tag = el.tagName.toLowerCase();
+		if (tag === 'input' && ['button', 'submit', 'reset'].includes((el.type || '').toLowerCase())) return el.value;
+		if (tag === 'img' || (tag === 'input' && el.type === 'image')) return el.getAttribute('alt') || '';
+		if (['input', 'textarea', 'select'].includes(tag)) return el.getAttribute('title') || el.getAttribute('placeholder') || '';
+		return el.textContent || el.getAttribute('title') || '';
+	};
+
+	const query = (root, part, deep) => {
+		switch (part.engine) {
+		case 'css':
+			return deep ? descendants(root, deep).filter(el => el.matches(part.value)) : [...root.querySelectorAll(part.value)];
+		case 'xpath': {
+			const res = document.evaluate(part.value, root, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+			const out = [];
+			for (let i = 0; i < res.snapshotLength; i++) {
+				const node = res.snapshotItem(i);
+				if (node.nodeType === Node.ELEMENT_NODE) out.push(node);
+			}
+			return out;
+		}
+		case 'text': {
+			const hit = el => !skipped.has(el.tagName) &&
+				(matches(el.textContent, part.value, part.exact) ||
+				(el.tagName === 'INPUT' && matches(el.value, part.value, part.exact)));
+			const hits = descendants(root, deep).filter(hit);
+			return hits.filter(el => ![...el.children].some(hit));
+		}
+		case 'role':
+			return descendants(root, deep).filter(el => roleOf(el) === part.value &&
+				(!part.name || matches(nameOf(el), part.name, part.exact)));
+		case 'label':
+			return descendants(root, deep).filter(el => roleOf(el) !== '' && labelOf(el) !== '' &&
+				matches(labelOf(el), part.value, part.exact));
+		case 'placeholder':
+			return descendants(root, deep).filter(el => el.hasAttribute('placeholder') &&
+				matches(el.getAttribute('placeholder'), part.value, part.exact));
+		case 'ref':
+			return descendants(root, true).filter(el => el.getAttribute('data-surfmate-ref') === part.value);
+		}
+		return [];
+	};
+
+	const describe = el => {
+		let selector = el.tagName.toLowerCase();
+		if (el.id) {
+			selector += '#' + el.id;
+		} else {
+			for (const c of [...el.classList].slice(0, 2)) selector += '.' + c;
+			if (el.hasAttribute('name')) selector += '[name="' + el.getAttribute('name') + '"]';
+		}
+		const text = nameOf(el).replace(/\s+/g, ' ').trim();
+		return { selector, role: roleOf(el), text: text.length > 60 ? text.slice(0, 57) + '...' : text };
+	};
+
+	const queryAll = (parts, deep) => {
+		let roots = [document];
+		let found = [];
+		parts.forEach((part, i) => {
+			found = [...new Set(roots.flatMap(root => query(root, part, deep)))];
+			if (i < parts.length - 1) {
+				roots = found.map(el => el.shadowRoot).filter(Boolean);
+			}
+		});
+		return found;
+	};
+`
+
+// queryAllJS returns every element matching the parsed selector
+const queryAllJS = `(parts, deep) => {` + locatorLibJS + `
+	return queryAll(parts, deep);
+}`
+
+// describeAllJS describes the first limit elements matching the parsed selector
+const describeAllJS = `(parts, deep, limit) => {` + locatorLibJS + `
+	return queryAll(parts, deep).slice(0, limit).map(describe);
+}`
+
+// suggestJS describes visible interactive elements that share words with the
+// last part of the parsed selector, best matches first
+const suggestJS = `(parts, deep, limit) => {` + locatorLibJS + `
+	const last = parts[parts.length - 1];
+	const words = norm(last.value + ' ' + (last.name || '')).split(/[^a-z0-9]+/).filter(w => w.length > 1);
+	const interactive = 'a, button, input, select, textarea, summary, [role], [onclick], [contenteditable], [tabindex]';
+	const scored = [];
+	for (const el of descendants(document, true)) {
+		if (!el.matches(interactive) || !el.checkVisibility()) continue;
+		const haystack = norm([nameOf(el), el.id, el.className, el.getAttribute('name'),
+			el.getAttribute('placeholder'), el.tagName, roleOf(el)].join(' '));
+		const score = words.filter(w => haystack.includes(w)).length;
+		if (score > 0) scored.push({ el, score });
+	}
+	scored.sort((a, b) => b.score - a.score);
+	return scored.slice(0, limit).map(s => describe(s.el));
+}`
+
+// diagnoseJS checks whether the element can receive input, returning the
+// failing reason and a detail, or null if it can. The viewport and coverage
+// checks only apply when pointer is true.
+const diagnoseJS = `function(pointer) {` + locatorLibJS + `
+	const el = this;
+	if (!el.isConnected || !el.checkVisibility({ opacityProperty: true, visibilityProperty: true })) {
+		return { reason: 'hidden', detail: 'it has no visible box (display, visibility or opacity hide it)' };
+	}
+	if (el.disabled || el.closest('fieldset[disabled]') || el.getAttribute('aria-disabled') === 'true') {
+		return { reason: 'disabled', detail: '' };
+	}
+	if (!pointer) return null;
+	el.scrollIntoView({ block: 'center', inline: 'center' });
+	const rect = el.getBoundingClientRect();
+	const x = rect.left + rect.width / 2;
+	const y = rect.top + rect.height / 2;
+	if (x < 0 || y < 0 || x > window.innerWidth || y > window.innerHeight) {
+		return { reason: 'outside_viewport', detail: 'it could not be scrolled into view' };
+	}
+	const top = el.getRootNode().elementFromPoint(x, y);
+	if (top && top !== el && !el.contains(top) && !top.contains(el)) {
+		const d = describe(top);
+		return { reason: 'covered', detail: d.selector + (d.text ? ' "' + d.text + '"' : '') };
+	}
+	return null;
+}`
+
+// pollInterval is how often element waits for a selector to match
+const pollInterval = 100 * time.Millisecond
+
+// maxCandidates caps the elements listed in an ElementError
+const maxCandidates = 5
+
+// query returns every element currently matching the parsed selector.
+// Must be called with m.mu held.
+func (m *Manager) query(selector string, parts []locatorPart) (rod.Elements, error) {
+	els, err := m.page.Timeout(m.config.BrowserTimeout).ElementsByJS(rod.Eval(queryAllJS, parts, m.config.DeepSelectors))
+	var evalErr *rod.EvalError
+	if errors.As(err, &evalErr) {
+		return nil, invalidSelector(selector, evalErr.Exception.Description)
+	}
+	return els, err
+}
+
+// candidates runs one of the describing scripts, ignoring failures since
+// candidates only decorate an error that is already being returned.
+// Must be called with m.mu held.
+func (m *Manager) candidates(js string, parts []locatorPart) []Candidate {
+	var out []Candidate
+	res, err := m.page.Timeout(m.config.BrowserTimeout).Eval(js, parts, m.config.DeepSelectors, maxCandidates)
+	if err == nil {
+		_ = res.Value.Unmarshal(&out)
+	}
+	return out
+}
+
+// element waits for the first element matching selector. Semantic locators
+// (text=, role=, label=, placeholder=) must match exactly one element.
+// Failures are reported as *ElementError.
+// Must be called with m.mu held.
+func (m *Manager) element(selector string) (*rod.Element, error) {
+	return m.elementWithin(selector, m.config.BrowserTimeout)
+}
+
+// elementWithin is element with a custom timeout.
+// Must be called with m.mu held.
+func (m *Manager) elementWithin(selector string, timeout time.Duration) (*rod.Element, error) {
+	parts, err := parseLocator(selector)
+	if err != nil {
+		return nil, err
+	}
+	strict := parts[len(parts)-1].semantic()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		els, err := m.query(selector, parts)
+		if err != nil {
+			return nil, err
+		}
+		if len(els) > 1 && strict {
+			return nil, &ElementError{
+				Selector:   selector,
+				Reason:     ReasonAmbiguous,
+				Matches:    len(els),
+				Candidates: m.candidates(describeAllJS, parts),
+			}
+		}
+		if len(els) > 0 {
+			return els[0], nil
+		}
+		if time.Now().After(deadline) {
+			return nil, &ElementError{
+				Selector:   selector,
+				Reason:     ReasonNotFound,
+				Detail:     fmt.Sprintf("no element matched within %v", timeout),
+				Candidates: m.candidates(suggestJS, parts),
+			}
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// actionableElement finds the element matching selector and checks that it
+// is visible and enabled. With pointer set it also checks that the element is
+// inside the viewport and not covered by another element.
+// Must be called with m.mu held.
+func (m *Manager) actionableElement(selector string, pointer bool) (*rod.Element, error) {
+	el, err := m.element(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := el.Eval(diagnoseJS, pointer)
+	if err != nil {
+		return nil, err
+	}
+	if res.Value.Nil() {
+		return el, nil
+	}
+
+	return nil, &ElementError{
+		Selector: selector,
+		Reason:   res.Value.Get("reason").Str(),
+		Matches:  1,
+		Detail:   res.Value.Get("detail").Str(),
+	}
+}
+
+// elements returns every element currently matching selector.
+// Must be called with m.mu held.
+func (m *Manager) elements(selector string) (rod.Elements, error) {
+	parts, err := parseLocator(selector)
+	if err != nil {
+		return nil, err
+	}
+	return m.query(selector, parts)
+}
@@ -0,0 +1,185 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultSettle is how long interaction tools wait for the page to settle
+// before reporting changes
+const DefaultSettle = 2 * time.Second
+
+// maxAppeared caps the newly visible elements listed in a StateChange
+const maxAppeared = 10
+
+// notableJS defines which elements a state snapshot tracks
+const notableJS = `
+	const notable = '[role=dialog], [role=alertdialog], dialog[open], [aria-modal=true], ' +
+		'[role=alert], [role=status], [aria-live=assertive], ' +
+		'a, button, input, select, textarea, h1, h2, h3, [role=button], [role=link], [role=tab]';
+	const isDialog = el => el.matches('[role=dialog], [role=alertdialog], dialog[open], [aria-modal=true]');
+	const visible = el => el.checkVisibility({ opacityProperty: true, visibilityProperty: true });
+	const notables = () => [...document.querySelectorAll(notable)].filter(visible);
+	const captcha = selectors => selectors.some(s => [...document.querySelectorAll(s)].some(visible));
+`
+
+// captureStateJS marks every visible notable element as seen
+const captureStateJS = `() => {` + notableJS + `
+	window.__surfmateSeen = new WeakSet(notables());
+}`
+
+// diffStateJS describes visible notable elements that were not seen by the
+// last captureStateJS. After a navigation the marker is gone and nothing is new.
+const diffStateJS = `(captchaSelectors, limit) => {` + locatorLibJS + notableJS + `
+	const seen = window.__surfmateSeen;
+	const fresh = seen ? notables().filter(el => !seen.has(el)) : [];
+	return {
+		dialogs: fresh.filter(isDialog).map(describe),
+		appeared: fresh.filter(el => !isDialog(el)).slice(0, limit).map(describe),
+		captcha: captcha(captchaSelectors),
+	};
+}`
+
+// PageState is a snapshot of the page taken before an action
+type PageState struct {
+	URL   string
+	Title string
+}
+
+// StateChange summarises what an action did to the page
+type StateChange struct {
+	URL          string      `json:"url"`
+	URLChanged   bool        `json:"url_changed"`
+	Title        string      `json:"title"`
+	TitleChanged bool        `json:"title_changed"`
+	NewDialogs   []Candidate `json:"new_dialogs,omitempty"`
+	Appeared     []Candidate `json:"appeared,omitempty" description:"Newly visible interactive elements, alerts and headings"`
+	Captcha      bool        `json:"captcha_found"`
+}
+
+func (c *StateChange) String() string {
+	var b strings.Builder
+	if c.URLChanged {
+		fmt.Fprintf(&b, "URL changed: %s\n", c.URL)
+	} else {
+		fmt.Fprintf(&b, "URL unchanged: %s\n", c.URL)
+	}
+	if c.TitleChanged {
+		fmt.Fprintf(&b, "Title changed: %s\n", c.Title)
+	}
+	for _, d := range c.NewDialogs {
+		fmt.Fprintf(&b, "Dialog opened: %s\n", d)
+	}
+	if len(c.Appeared) > 0 {
+		b.WriteString("Newly visible:\n")
+		for _, el := range c.Appeared {
+			fmt.Fprintf(&b, "  - %s\n", el)
+		}
+	}
+	fmt.Fprintf(&b, "Captcha detected: %v", c.Captcha)
+	return b.String()
+}
+
+// ObserveError reports that an action ran but what it changed on the page
+// could not be read
+type ObserveError struct {
+	Err error
+}
+
+func (e *ObserveError) Error() string {
+	return "the action ran, but its page changes could not be read: " + e.Err.Error()
+}
+
+func (e *ObserveError) Unwrap() error {
+	return e.Err
+}
+
+// captureState snapshots the page so diffState can report what a following
+// action changed.
+// Must be called with m.mu held.
+func (m *Manager) captureState() (*PageState, error) {
+	info, err := m.page.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := m.page.Timeout(m.config.BrowserTimeout).Eval(captureStateJS); err != nil {
+		return nil, err
+	}
+
+	return &PageState{URL: info.URL, Title: info.Title}, nil
+}
+
+// diffState waits up to settle for the page to stop changing, then compares
+// it against before. Errors other than a blocked navigation are returned as
+// an *ObserveError, as the action itself succeeded.
+// Must be called with m.mu held.
+func (m *Manager) diffState(before *PageState, settle time.Duration) (*StateChange, error) {
+	// Both waits share the settle deadline and may time out on busy pages;
+	// the diff is still useful then
+	page := m.page.Timeout(settle)
+	_ = page.WaitLoad()
+	_ = page.WaitDOMStable(300*time.Millisecond, 0)
+
+	// Navigations the action started may be blocked only after it returned
+	if err := m.interceptor.violation(); err != nil {
+		return nil, err
+	}
+
+	info, err := m.page.Info()
+	if err != nil {
+		return nil, &ObserveError{Err: err}
+	}
+
+	res, err := m.page.Timeout(m.config.BrowserTimeout).Eval(diffStateJS, CaptchaSelectors, maxAppeared)
+	if err != nil {
+		return nil, &ObserveError{Err: err}
+	}
+
+	change := &StateChange{
+		URL:          info.URL,
+		URLChanged:   info.URL != before.URL,
+		Title:        info.Title,
+		TitleChanged: info.Title != before.Title,
+		Captcha:      res.Value.Get("captcha").Bool(),
+	}
+	if err := res.Value.Get("dialogs").Unmarshal(&change.NewDialogs); err != nil {
+		return nil, &ObserveError{Err: err}
+	}
+	if err := res.Value.Get("appeared").Unmarshal(&change.Appeared); err != nil {
+		return nil, &ObserveError{Err: err}
+	}
+	return change, nil
+}
+
+// Observe runs a click or type action and reports what it changed on the
+// page, waiting up to settle for the page to stop changing first. The page
+// is captured, acted on and compared under one lock, so other calls can't
+// change it in between, except while a sensitive action awaits approval.
+func (m *Manager) Observe(settle time.Duration, a Action) (*StateChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.page == nil {
+		return nil, fmt.Errorf("browser is not open")
+	}
+
+	before, err := m.captureState()
+	if err != nil {
+		return nil, err
+	}
+
+	switch a.Action {
+	case ActionClick:
+		err = m.click(a.Selector)
+	case ActionType:
+		err = m.typeText(a.Selector, a.Text, a.Submit)
+	default:
+		return nil, fmt.Errorf("changes can't be reported for %s", a.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.diffState(before, settle)
+}
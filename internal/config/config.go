@@ -1,59 +1,493 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/afalcongonzalez/surfmate.io/internal/secrets"
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds the application configuration
 type Config struct {
-	BrowserPath    string
-	BrowserTimeout time.Duration
-	ViewportWidth  int
-	ViewportHeight int
-	Headless       bool
+	BrowserPath         string
+	BrowserTimeout      time.Duration
+	ViewportWidth       int
+	ViewportHeight      int
+	Headless            bool
+	DeepSelectors       bool
+	OutputDir           string
+	Record              bool
+	RecordingFormat     string
+	Trace               bool
+	TraceShots          bool
+	UserAgent           string
+	Locale              string
+	Timezone            string
+	Geolocation         string
+	CDPURL              string
+	AutoRelaunch        bool
+	BrowserFlags        string
+	ProxyServer         string
+	ProxyBypass         string
+	ProxyUsername       string
+	ProxyPassword       string
+	ExtensionsDir       string
+	Port                int
+	BaseURL             string
+	APIKeys             string
+	CORSOrigins         string
+	URLAllow            string
+	URLDeny             string
+	AllowPrivateNetwork bool
+	Approval            string
+	ApprovalTimeout     time.Duration
+	ApproveClickText    string
+	ApproveURLs         string
+	ApprovePaymentForms bool
+
+	// Secrets are typed as {{secret:name}} and masked in all output. They
+	// come from the secrets map of the config file and SURFMATE_SECRET_<NAME>
+	// environment variables, never from flags.
+	Secrets map[string]string
+
+	// File is the config file that was read, if any
+	File string
+
+	// sources records where each setting not left at its default came from
+	sources map[string]string
+}
+
+// secretEnvPrefix starts the environment variables holding secrets
+const secretEnvPrefix = "SURFMATE_SECRET_"
+
+// setting is one configuration option. It is read from key in the config
+// file, then from the first of env that is set, then from the flag named
+// like key with dashes. An environment variable set to "" resets the
+// setting to its default, unless another of env has a value.
+type setting struct {
+	key    string
+	env    []string
+	usage  string
+	secret bool
+	field  func(c *Config) any
 }
 
-// Load returns configuration from environment variables with defaults
-func Load() *Config {
+var settings = []setting{
+	{key: "browser_path", env: []string{"BROWSER_PATH"}, usage: "Chrome or Chromium executable, downloaded when empty", field: func(c *Config) any { return &c.BrowserPath }},
+	{key: "browser_timeout", env: []string{"BROWSER_TIMEOUT"}, usage: "Timeout for page loads and element lookups, e.g. 30s", field: func(c *Config) any { return &c.BrowserTimeout }},
+	{key: "viewport_width", env: []string{"VIEWPORT_WIDTH"}, usage: "Viewport width in CSS pixels", field: func(c *Config) any { return &c.ViewportWidth }},
+	{key: "viewport_height", env: []string{"VIEWPORT_HEIGHT"}, usage: "Viewport height in CSS pixels", field: func(c *Config) any { return &c.ViewportHeight }},
+	{key: "headless", env: []string{"HEADLESS"}, usage: "Run the browser without a window", field: func(c *Config) any { return &c.Headless }},
+	{key: "deep_selectors", env: []string{"DEEP_SELECTORS"}, usage: "Look for elements inside nested open shadow roots; iframes are not searched", field: func(c *Config) any { return &c.DeepSelectors }},
+	{key: "output_dir", env: []string{"OUTPUT_DIR"}, usage: "Directory for screenshots, PDFs, recordings and traces", field: func(c *Config) any { return &c.OutputDir }},
+	{key: "record", env: []string{"RECORD"}, usage: "Record the session from launch", field: func(c *Config) any { return &c.Record }},
+	{key: "recording_format", env: []string{"RECORDING_FORMAT"}, usage: "Recording format: gif or zip", field: func(c *Config) any { return &c.RecordingFormat }},
+	{key: "trace", env: []string{"TRACE"}, usage: "Write every tool call to a trace file", field: func(c *Config) any { return &c.Trace }},
+	{key: "trace_screenshots", env: []string{"TRACE_SCREENSHOTS"}, usage: "Save screenshots before and after each traced call", field: func(c *Config) any { return &c.TraceShots }},
+	{key: "user_agent", env: []string{"USER_AGENT"}, usage: "User agent override", field: func(c *Config) any { return &c.UserAgent }},
+	{key: "locale", env: []string{"LOCALE"}, usage: "Locale override such as de-DE", field: func(c *Config) any { return &c.Locale }},
+	{key: "timezone", env: []string{"TIMEZONE"}, usage: "IANA timezone override such as Europe/Berlin", field: func(c *Config) any { return &c.Timezone }},
+	{key: "geolocation", env: []string{"GEOLOCATION"}, usage: "Geolocation override as lat,lon[,accuracy]", field: func(c *Config) any { return &c.Geolocation }},
+	{key: "cdp_url", env: []string{"BROWSER_WS_ENDPOINT", "CDP_URL"}, usage: "Attach to the Chrome at this DevTools URL instead of launching one", field: func(c *Config) any { return &c.CDPURL }},
+	{key: "auto_relaunch", env: []string{"AUTO_RELAUNCH"}, usage: "Relaunch the browser on the next call after it crashes", field: func(c *Config) any { return &c.AutoRelaunch }},
+	{key: "browser_flags", env: []string{"BROWSER_FLAGS"}, usage: "Extra Chrome flags separated by spaces, e.g. --lang=de --disable-gpu", field: func(c *Config) any { return &c.BrowserFlags }},
+	{key: "proxy_server", env: []string{"PROXY_SERVER"}, usage: "Proxy server as host:port or scheme://[user:pass@]host:port", field: func(c *Config) any { return &c.ProxyServer }},
+	{key: "proxy_bypass", env: []string{"PROXY_BYPASS"}, usage: "Comma separated hosts that skip the proxy", field: func(c *Config) any { return &c.ProxyBypass }},
+	{key: "proxy_username", env: []string{"PROXY_USERNAME"}, usage: "Proxy username", field: func(c *Config) any { return &c.ProxyUsername }},
+	{key: "proxy_password", env: []string{"PROXY_PASSWORD"}, usage: "Proxy password", secret: true, field: func(c *Config) any { return &c.ProxyPassword }},
+	{key: "extensions_dir", env: []string{"EXTENSIONS_DIR"}, usage: "Unpacked extension, or a directory of them, to load", field: func(c *Config) any { return &c.ExtensionsDir }},
+	{key: "port", env: []string{"PORT"}, usage: "HTTP server port (only used with -http and -mcp-http)", field: func(c *Config) any { return &c.Port }},
+	{key: "base_url", env: []string{"BASE_URL"}, usage: "Public URL of the server when behind a proxy, used for the -mcp-http message endpoint", field: func(c *Config) any { return &c.BaseURL }},
+	{key: "api_keys", env: []string{"API_KEYS"}, usage: "Comma separated tokens the HTTP server accepts as a bearer token or X-API-Key header", secret: true, field: func(c *Config) any { return &c.APIKeys }},
+	{key: "url_allow", env: []string{"URL_ALLOW"}, usage: "Comma separated rules for the only URLs the browser may open, e.g. intranet.corp,*.vendor.com", field: func(c *Config) any { return &c.URLAllow }},
+	{key: "url_deny", env: []string{"URL_DENY"}, usage: "Comma separated rules for URLs the browser may never open, e.g. mybank.com,re:^https?://[^/]*pay", field: func(c *Config) any { return &c.URLDeny }},
+	{key: "allow_private_network", env: []string{"ALLOW_PRIVATE_NETWORK"}, usage: "Let the browser open localhost and private network addresses, or host names resolving to them. Only the controlled tab and its frames are checked, not popups or tabs it opens", field: func(c *Config) any { return &c.AllowPrivateNetwork }},
+	{key: "approval", env: []string{"APPROVAL"}, usage: "Ask before sensitive actions: off, browser (a prompt over the page) or terminal", field: func(c *Config) any { return &c.Approval }},
+	{key: "approval_timeout", env: []string{"APPROVAL_TIMEOUT"}, usage: "How long to wait for an answer before denying", field: func(c *Config) any { return &c.ApprovalTimeout }},
+	{key: "approve_click_text", env: []string{"APPROVE_CLICK_TEXT"}, usage: "Comma separated words; clicking an element whose text contains one needs approval", field: func(c *Config) any { return &c.ApproveClickText }},
+	{key: "approve_urls", env: []string{"APPROVE_URLS"}, usage: "Comma separated URL rules, as for url_allow, for pages that need approval to open", field: func(c *Config) any { return &c.ApproveURLs }},
+	{key: "approve_payment_forms", env: []string{"APPROVE_PAYMENT_FORMS"}, usage: "Submitting a form with card fields needs approval", field: func(c *Config) any { return &c.ApprovePaymentForms }},
+	{key: "cors_origins", env: []string{"CORS_ORIGINS"}, usage: "Comma separated origins allowed to call the HTTP server from a web page, * for any", field: func(c *Config) any { return &c.CORSOrigins }},
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
 	return &Config{
-		BrowserPath:    getEnv("BROWSER_PATH", ""),
-		BrowserTimeout: getDurationEnv("BROWSER_TIMEOUT", 30*time.Second),
-		ViewportWidth:  getIntEnv("VIEWPORT_WIDTH", 1280),
-		ViewportHeight: getIntEnv("VIEWPORT_HEIGHT", 800),
-		Headless:       getBoolEnv("HEADLESS", false),
+		BrowserTimeout:      30 * time.Second,
+		ViewportWidth:       1280,
+		ViewportHeight:      800,
+		OutputDir:           filepath.Join(os.TempDir(), "surfmate.io"),
+		RecordingFormat:     "gif",
+		AutoRelaunch:        true,
+		Port:                8080,
+		CORSOrigins:         "*",
+		Approval:            "off",
+		ApprovalTimeout:     2 * time.Minute,
+		ApproveClickText:    "delete,remove,pay,purchase,buy,place order,checkout,transfer,unsubscribe",
+		ApprovePaymentForms: true,
 	}
 }
 
-func getBoolEnv(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		return value == "true" || value == "1"
+// DefaultPath returns the config file read when -config is not given:
+// config.yaml in the surfmate.io folder of the user config directory, or
+// config.toml there if only that exists
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(dir, "surfmate.io", "config.yaml")
+	if _, err := os.Stat(path); err != nil {
+		if alt := filepath.Join(dir, "surfmate.io", "config.toml"); fileExists(alt) {
+			return alt
+		}
 	}
-	return defaultValue
+	return path
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// RegisterFlags defines -config and a flag for every setting on fs. The
+// returned string is the -config path.
+func RegisterFlags(fs *flag.FlagSet) *string {
+	path := fs.String("config", "", "Config file, YAML or TOML (default: "+DefaultPath()+")")
+	def := Default()
+	for _, s := range settings {
+		f := &settingFlag{value: format(s.field(def))}
+		_, f.isBool = s.field(def).(*bool)
+		usage := s.usage
+		if len(s.env) > 0 {
+			usage += " [" + strings.Join(s.env, ", ") + "]"
+		}
+		fs.Var(f, flagName(s.key), usage)
 	}
-	return defaultValue
+	return path
 }
 
-func getIntEnv(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if i, err := strconv.Atoi(value); err == nil {
-			return i
+// Load returns the configuration from the defaults, the config file at path,
+// environment variables and the flags set on fs, each overriding the ones
+// before. An empty path reads DefaultPath if it exists, and fs may be nil.
+func Load(path string, fs *flag.FlagSet) (*Config, error) {
+	c := Default()
+	c.Secrets = map[string]string{}
+	c.sources = map[string]string{}
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+	if path != "" && (explicit || fileExists(path)) {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if name, ok := strings.CutPrefix(key, secretEnvPrefix); ok && name != "" && value != "" {
+			c.Secrets[strings.ToLower(name)] = value
 		}
 	}
-	return defaultValue
+
+	def := Default()
+	for _, s := range settings {
+		env, value, ok := lookupEnv(s.env)
+		if !ok {
+			continue
+		}
+		if value == "" {
+			value = format(s.field(def))
+		}
+		if err := set(s.field(c), value); err != nil {
+			return nil, fmt.Errorf("environment variable %s: %w", env, err)
+		}
+		c.sources[s.key] = env
+	}
+
+	if fs != nil {
+		var err error
+		fs.Visit(func(f *flag.Flag) {
+			s, ok := lookup(strings.ReplaceAll(f.Name, "-", "_"))
+			if !ok || err != nil {
+				return
+			}
+			if err = set(s.field(c), f.Value.String()); err != nil {
+				err = fmt.Errorf("flag -%s: %w", f.Name, err)
+				return
+			}
+			c.sources[s.key] = "-" + f.Name
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if d, err := time.ParseDuration(value); err == nil {
-			return d
+// loadFile reads the YAML or TOML config file at path, by its extension
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	case ".yaml", ".yml", "":
+		err = yaml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	if raw, ok := values["secrets"]; ok {
+		secrets, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("config file %s: secrets must map names to values", path)
+		}
+		for name, value := range secrets {
+			switch value.(type) {
+			case map[string]any, []any, nil:
+				return fmt.Errorf("config file %s: secret %s must be a single value", path, name)
+			}
+			c.Secrets[name] = fmt.Sprint(value)
+		}
+		delete(values, "secrets")
+	}
+
+	for key, value := range values {
+		s, ok := lookup(key)
+		if !ok {
+			return fmt.Errorf("config file %s: unknown setting %q (run \"surfmate.io config print\" to list them)", path, key)
+		}
+		switch value.(type) {
+		case map[string]any, []any:
+			return fmt.Errorf("config file %s: %s must be a single value", path, key)
+		}
+		if value == nil {
+			value = ""
+		}
+		if err := set(s.field(c), fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
 		}
+		c.sources[key] = path
+	}
+	c.File = path
+	return nil
+}
+
+// Validate checks that the settings are usable
+func (c *Config) Validate() error {
+	var errs []error
+	if c.BrowserTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("browser_timeout must be positive, got %s", c.BrowserTimeout))
+	}
+	if c.ViewportWidth <= 0 || c.ViewportHeight <= 0 {
+		errs = append(errs, fmt.Errorf("viewport_width and viewport_height must be positive, got %dx%d", c.ViewportWidth, c.ViewportHeight))
+	}
+	if c.OutputDir == "" {
+		errs = append(errs, errors.New("output_dir must not be empty"))
+	}
+	if c.RecordingFormat != "gif" && c.RecordingFormat != "zip" {
+		errs = append(errs, fmt.Errorf("recording_format must be gif or zip, got %q", c.RecordingFormat))
+	}
+	if c.Approval != "off" && c.Approval != "browser" && c.Approval != "terminal" {
+		errs = append(errs, fmt.Errorf("approval must be off, browser or terminal, got %q", c.Approval))
+	}
+	if c.ApprovalTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("approval_timeout must be positive, got %s", c.ApprovalTimeout))
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535, got %d", c.Port))
+	}
+	if c.BaseURL != "" {
+		if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("base_url must be an http or https URL without a query, got %q", c.BaseURL))
+		}
+	}
+	if c.ExtensionsDir != "" && !fileExists(c.ExtensionsDir) {
+		errs = append(errs, fmt.Errorf("extensions_dir %s does not exist", c.ExtensionsDir))
 	}
-	return defaultValue
+	if c.BrowserPath != "" {
+		if _, err := exec.LookPath(c.BrowserPath); err != nil {
+			errs = append(errs, fmt.Errorf("browser_path %s is not an executable", c.BrowserPath))
+		}
+	}
+	for name := range c.Secrets {
+		if !secrets.ValidName(name) {
+			errs = append(errs, fmt.Errorf("secret name %q may only contain letters, digits, '_', '.' and '-'", name))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
+
+// YAML returns the configuration as a config file, noting where each
+// setting came from. Secrets are masked.
+func (c *Config) YAML() ([]byte, error) {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range settings {
+		value := format(s.field(c))
+		if s.secret && value != "" {
+			value = "********"
+		}
+		v := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		// Quote strings that would otherwise read as numbers or booleans
+		if _, ok := s.field(c).(*string); ok {
+			v.Tag = "!!str"
+		}
+		if src, ok := c.sources[s.key]; ok {
+			v.LineComment = "from " + src
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: s.key}, v)
+	}
+
+	if len(c.Secrets) > 0 {
+		names := make([]string, 0, len(c.Secrets))
+		for name := range c.Secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		secrets := &yaml.Node{Kind: yaml.MappingNode}
+		for _, name := range names {
+			secrets.Content = append(secrets.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "********", Tag: "!!str"})
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "secrets"}, secrets)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// List splits a comma separated setting into its trimmed, non-empty items
+func List(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// settingFlag is a flag holding the raw value of a setting until Load
+// parses it with the others
+type settingFlag struct {
+	value  string
+	isBool bool
+}
+
+func (f *settingFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *settingFlag) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+func (f *settingFlag) IsBoolFlag() bool { return f.isBool }
+
+func lookup(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// set parses value into the setting field
+func set(field any, value string) error {
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		*f = b
+	case *int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		*f = i
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 1m", value)
+		}
+		*f = d
+	}
+	return nil
+}
+
+// lookupEnv returns the first of names with a value, or else the first that
+// is set but empty
+func lookupEnv(names []string) (name, value string, ok bool) {
+	for _, n := range names {
+		if v, set := os.LookupEnv(n); set && (v != "" || !ok) {
+			name, value, ok = n, v, true
+			if v != "" {
+				break
+			}
+		}
+	}
+	return name, value, ok
+}
+
+// format returns the setting field as set reads it
+func format(field any) string {
+	switch f := field.(type) {
+	case *string:
+		return *f
+	case *bool:
+		return strconv.FormatBool(*f)
+	case *int:
+		return strconv.Itoa(*f)
+	case *time.Duration:
+		return f.String()
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name         string
+		file         string
+		env          map[string]string
+		args         []string
+		wantProxy    string
+		wantHeadless bool
+		wantCDPURL   string
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:         "file",
+			file:         "proxy_server: file:8080\nheadless: true\ncdp_url: http://file:9222\n",
+			wantProxy:    "file:8080",
+			wantHeadless: true,
+			wantCDPURL:   "http://file:9222",
+		},
+		{
+			name:         "env overrides file",
+			file:         "proxy_server: file:8080\nheadless: true\n",
+			env:          map[string]string{"PROXY_SERVER": "env:8080", "HEADLESS": "false"},
+			wantProxy:    "env:8080",
+			wantHeadless: false,
+		},
+		{
+			name:         "flag overrides env and file",
+			file:         "proxy_server: file:8080\n",
+			env:          map[string]string{"PROXY_SERVER": "env:8080"},
+			args:         []string{"-proxy-server", "flag:8080", "-headless"},
+			wantProxy:    "flag:8080",
+			wantHeadless: true,
+		},
+		{
+			name:         "empty env resets file value",
+			file:         "proxy_server: file:8080\nheadless: true\n",
+			env:          map[string]string{"PROXY_SERVER": "", "HEADLESS": ""},
+			wantProxy:    "",
+			wantHeadless: false,
+		},
+		{
+			name:       "first env with a value wins",
+			file:       "cdp_url: http://file:9222\n",
+			env:        map[string]string{"BROWSER_WS_ENDPOINT": "", "CDP_URL": "http://env:9222"},
+			wantCDPURL: "http://env:9222",
+		},
+		{
+			name:       "first env wins over later ones",
+			env:        map[string]string{"BROWSER_WS_ENDPOINT": "ws://first", "CDP_URL": "http://second:9222"},
+			wantCDPURL: "ws://first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range settings {
+				for _, env := range s.env {
+					unsetenv(t, env)
+				}
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			RegisterFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := Load(path, fs)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if c.ProxyServer != tt.wantProxy {
+				t.Errorf("ProxyServer = %q, want %q", c.ProxyServer, tt.wantProxy)
+			}
+			if c.Headless != tt.wantHeadless {
+				t.Errorf("Headless = %v, want %v", c.Headless, tt.wantHeadless)
+			}
+			if c.CDPURL != tt.wantCDPURL {
+				t.Errorf("CDPURL = %q, want %q", c.CDPURL, tt.wantCDPURL)
+			}
+		})
+	}
+}
+
+// unsetenv unsets key for the rest of the test, restoring it afterwards
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+	if v, ok := os.LookupEnv(key); ok {
+		t.Setenv(key, v)
+		os.Unsetenv(key)
+	}
+}
@@ -0,0 +1,170 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// referenceRe matches a {{secret:name}} reference
+var referenceRe = regexp.MustCompile(`\{\{\s*secret:([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// nameRe matches a valid secret name
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// MinLength is the shortest secret unlikely to turn up in page text by
+// chance. Shorter secrets work, but Mask also replaces every unrelated
+// occurrence of them.
+const MinLength = 8
+
+// Store holds named secrets the agent can type as {{secret:name}} without
+// seeing their values, and masks those values wherever they show up
+type Store struct {
+	values map[string]string
+	// names sorted by value length, longest first, so a secret containing
+	// another is masked whole
+	names []string
+}
+
+// New returns a store of the named secrets. Empty values are left out.
+func New(values map[string]string) *Store {
+	s := &Store{values: map[string]string{}}
+	for name, value := range values {
+		if value != "" {
+			s.values[name] = value
+			s.names = append(s.names, name)
+		}
+	}
+	sort.Slice(s.names, func(i, j int) bool {
+		a, b := s.values[s.names[i]], s.values[s.names[j]]
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return s.names[i] < s.names[j]
+	})
+	return s
+}
+
+// ValidName reports whether name can be used in a reference
+func ValidName(name string) bool {
+	return nameRe.MatchString(name)
+}
+
+// Reference returns the {{secret:name}} reference to a secret
+func Reference(name string) string {
+	return "{{secret:" + name + "}}"
+}
+
+// Names returns the names of the secrets, sorted
+func (s *Store) Names() []string {
+	names := append([]string(nil), s.names...)
+	sort.Strings(names)
+	return names
+}
+
+// Short returns the names of the secrets shorter than MinLength, sorted
+func (s *Store) Short() []string {
+	var short []string
+	for _, name := range s.Names() {
+		if len(s.values[name]) < MinLength {
+			short = append(short, name)
+		}
+	}
+	return short
+}
+
+// Empty reports whether the store holds no secrets
+func (s *Store) Empty() bool {
+	return len(s.names) == 0
+}
+
+// Resolve replaces the secret references in text with their values
+func (s *Store) Resolve(text string) (string, error) {
+	var err error
+	resolved := referenceRe.ReplaceAllStringFunc(text, func(ref string) string {
+		name := referenceRe.FindStringSubmatch(ref)[1]
+		value, ok := s.values[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("unknown secret %q (configured: %s)", name, s.list())
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+func (s *Store) list() string {
+	if s.Empty() {
+		return "none"
+	}
+	return strings.Join(s.Names(), ", ")
+}
+
+// Mask replaces the secret values in text with their references, including
+// values escaped in a URL or a JSON string
+func (s *Store) Mask(text string) string {
+	for _, name := range s.names {
+		for _, form := range textForms(s.values[name]) {
+			text = strings.ReplaceAll(text, string(form), Reference(name))
+		}
+	}
+	return text
+}
+
+// MaskJSON replaces the secret values in encoded JSON with their
+// references, as they appear there with or without HTML escaping
+func (s *Store) MaskJSON(data []byte) []byte {
+	for _, name := range s.names {
+		ref := []byte(Reference(name))
+		var forms [][]byte
+		for _, form := range textForms(s.values[name]) {
+			forms = appendForms(forms, jsonForms(string(form))...)
+		}
+		for _, form := range forms {
+			data = bytes.ReplaceAll(data, form, ref)
+		}
+	}
+	return data
+}
+
+// textForms returns value as it can appear in text: as is, escaped in a URL
+// query or path, or inside a JSON string
+func textForms(value string) [][]byte {
+	forms := [][]byte{[]byte(value)}
+	forms = appendForms(forms, []byte(url.QueryEscape(value)), []byte(url.PathEscape(value)))
+	return appendForms(forms, jsonForms(value)...)
+}
+
+// appendForms appends the forms not in forms yet
+func appendForms(forms [][]byte, more ...[]byte) [][]byte {
+	for _, form := range more {
+		if !slices.ContainsFunc(forms, func(f []byte) bool { return bytes.Equal(f, form) }) {
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
+// jsonForms returns value as it can appear inside a JSON string
+func jsonForms(value string) [][]byte {
+	forms := [][]byte{[]byte(value)}
+	for _, escapeHTML := range []bool{true, false} {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(escapeHTML)
+		if enc.Encode(value) != nil {
+			continue
+		}
+		// Drop the quotes and trailing newline
+		form := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+		forms = appendForms(forms, form[1:len(form)-1])
+	}
+	return forms
+}
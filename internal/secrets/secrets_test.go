@@ -0,0 +1,132 @@
+package secrets
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets map[string]string
+		text    string
+		want    string
+	}{
+		{
+			name:    "plain",
+			secrets: map[string]string{"pw": "correct-horse"},
+			text:    "password is correct-horse.",
+			want:    "password is {{secret:pw}}.",
+		},
+		{
+			name:    "every occurrence",
+			secrets: map[string]string{"pw": "correct-horse"},
+			text:    "correct-horse correct-horse",
+			want:    "{{secret:pw}} {{secret:pw}}",
+		},
+		{
+			name:    "secret containing another is masked whole",
+			secrets: map[string]string{"short": "hunter22", "long": "hunter22hunter22"},
+			text:    "a hunter22hunter22 b hunter22",
+			want:    "a {{secret:long}} b {{secret:short}}",
+		},
+		{
+			name:    "overlapping secrets mask the longer first",
+			secrets: map[string]string{"a": "abcdefgh", "b": "efghijklmn"},
+			text:    "abcdefghijklmn",
+			want:    "abcd{{secret:b}}",
+		},
+		{
+			name:    "query escaped",
+			secrets: map[string]string{"pw": "p&ss w0rd!"},
+			text:    "https://example.com/login?pw=p%26ss+w0rd%21",
+			want:    "https://example.com/login?pw={{secret:pw}}",
+		},
+		{
+			name:    "path escaped",
+			secrets: map[string]string{"pw": "p&ss w0rd!"},
+			text:    "https://example.com/p&ss%20w0rd%21/",
+			want:    "https://example.com/{{secret:pw}}/",
+		},
+		{
+			name:    "JSON escaped",
+			secrets: map[string]string{"pw": `q"uo\te<d>`},
+			text:    `{"pw":"q\"uo\\te<d>"} {"pw":"q\"uo\\te<d>"}`,
+			want:    `{"pw":"{{secret:pw}}"} {"pw":"{{secret:pw}}"}`,
+		},
+		{
+			name:    "short secret masks unrelated text",
+			secrets: map[string]string{"pin": "42"},
+			text:    "PIN 42, page 142",
+			want:    "PIN {{secret:pin}}, page 1{{secret:pin}}",
+		},
+		{
+			name:    "empty secret is ignored",
+			secrets: map[string]string{"none": ""},
+			text:    "nothing to mask",
+			want:    "nothing to mask",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.secrets).Mask(tt.text); got != tt.want {
+				t.Errorf("Mask(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets map[string]string
+		value   any
+		want    string
+	}{
+		{
+			name:    "HTML escaped",
+			secrets: map[string]string{"pw": "<p&ss>"},
+			value:   map[string]string{"text": "typed <p&ss>"},
+			want:    `{"text":"typed {{secret:pw}}"}`,
+		},
+		{
+			name:    "quotes and backslashes",
+			secrets: map[string]string{"pw": `a"b\c-d`},
+			value:   map[string]string{"text": `a"b\c-d`},
+			want:    `{"text":"{{secret:pw}}"}`,
+		},
+		{
+			name:    "query escaped inside JSON",
+			secrets: map[string]string{"pw": "p&ss w0rd!"},
+			value:   map[string]string{"url": "https://example.com/?pw=p%26ss+w0rd%21"},
+			want:    `{"url":"https://example.com/?pw={{secret:pw}}"}`,
+		},
+		{
+			name:    "secret containing another is masked whole",
+			secrets: map[string]string{"short": "hunter22", "long": "hunter22hunter22"},
+			value:   []string{"hunter22hunter22", "hunter22"},
+			want:    `["{{secret:long}}","{{secret:short}}"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(New(tt.secrets).MaskJSON(data)); got != tt.want {
+				t.Errorf("MaskJSON(%s) = %s, want %s", data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShort(t *testing.T) {
+	s := New(map[string]string{"pin": "1234", "pw": "long-enough", "code": "1234567"})
+	if got, want := s.Short(), []string{"code", "pin"}; !slices.Equal(got, want) {
+		t.Errorf("Short() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// authMiddleware rejects requests without one of the configured API keys,
+// given as a bearer token or in the X-API-Key header. The spec and index
+// stay public so clients can be set up from them. With no keys configured
+// every request is let through.
+func (s *HTTPServer) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.apiKeys) == 0 || r.Method == "OPTIONS" || r.URL.Path == "/" || r.URL.Path == "/openapi.yaml" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !s.validKey(requestKey(r)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="surfmate.io"`)
+			errorResponse(w, http.StatusUnauthorized, "missing or invalid API key: send it as Authorization: Bearer <key> or X-API-Key: <key>")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestKey returns the API key sent with r, if any
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// validKey reports whether key is one of the API keys. Keys are hashed to
+// the same length and all compared, so timing reveals nothing about them.
+func (s *HTTPServer) validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(key))
+	match := 0
+	for _, k := range s.apiKeys {
+		want := sha256.Sum256([]byte(k))
+		match |= subtle.ConstantTimeCompare(sum[:], want[:])
+	}
+	return match == 1
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for a request
+// from origin, or "" when the origin is not allowed
+func (s *HTTPServer) allowOrigin(origin string) string {
+	if slices.Contains(s.corsOrigins, "*") {
+		return "*"
+	}
+	if origin != "" && slices.Contains(s.corsOrigins, origin) {
+		return origin
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidKey(t *testing.T) {
+	s := &HTTPServer{apiKeys: []string{"first-key-0123456789", "second"}}
+
+	tests := []struct {
+		name string
+		key  string
+		want bool
+	}{
+		{name: "first key", key: "first-key-0123456789", want: true},
+		{name: "second key of another length", key: "second", want: true},
+		{name: "missing", key: "", want: false},
+		{name: "wrong", key: "third", want: false},
+		{name: "prefix", key: "first-key", want: false},
+		{name: "extra characters", key: "second ", want: false},
+		{name: "different case", key: "SECOND", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.validKey(tt.key); got != tt.want {
+				t.Errorf("validKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		method  string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{name: "bearer", keys: []string{"k1"}, path: "/navigate", headers: map[string]string{"Authorization": "Bearer k1"}, want: http.StatusOK},
+		{name: "bearer any case", keys: []string{"k1"}, path: "/navigate", headers: map[string]string{"Authorization": "bearer k1"}, want: http.StatusOK},
+		{name: "X-API-Key", keys: []string{"k1", "k2"}, path: "/navigate", headers: map[string]string{"X-API-Key": "k2"}, want: http.StatusOK},
+		{name: "missing key", keys: []string{"k1"}, path: "/navigate", want: http.StatusUnauthorized},
+		{name: "wrong key", keys: []string{"k1"}, path: "/navigate", headers: map[string]string{"Authorization": "Bearer k2"}, want: http.StatusUnauthorized},
+		{name: "basic scheme", keys: []string{"k1"}, path: "/navigate", headers: map[string]string{"Authorization": "Basic k1"}, want: http.StatusUnauthorized},
+		{name: "wrong X-API-Key beats bearer", keys: []string{"k1"}, path: "/navigate", headers: map[string]string{"X-API-Key": "bad", "Authorization": "Bearer k1"}, want: http.StatusUnauthorized},
+		{name: "spec is public", keys: []string{"k1"}, path: "/openapi.yaml", want: http.StatusOK},
+		{name: "index is public", keys: []string{"k1"}, path: "/", want: http.StatusOK},
+		{name: "preflight is public", keys: []string{"k1"}, method: http.MethodOptions, path: "/navigate", want: http.StatusOK},
+		{name: "no keys configured", path: "/navigate", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &HTTPServer{apiKeys: tt.keys}
+			h := s.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, tt.path, nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestAllowOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		want    string
+	}{
+		{name: "none configured", origin: "https://chat.openai.com", want: ""},
+		{name: "listed", origins: []string{"https://a.example", "https://chat.openai.com"}, origin: "https://chat.openai.com", want: "https://chat.openai.com"},
+		{name: "not listed", origins: []string{"https://a.example"}, origin: "https://evil.example", want: ""},
+		{name: "exact match only", origins: []string{"https://a.example"}, origin: "https://a.example.evil.com", want: ""},
+		{name: "scheme matters", origins: []string{"https://a.example"}, origin: "http://a.example", want: ""},
+		{name: "no origin header", origins: []string{"https://a.example"}, origin: "", want: ""},
+		{name: "wildcard", origins: []string{"*"}, origin: "https://any.example", want: "*"},
+		{name: "wildcard without origin", origins: []string{"*"}, origin: "", want: "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &HTTPServer{corsOrigins: tt.origins}
+			if got := s.allowOrigin(tt.origin); got != tt.want {
+				t.Errorf("allowOrigin(%q) = %q, want %q", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
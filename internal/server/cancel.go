@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// message is the part of a JSON-RPC message needed to track requests and
+// their cancellation, and to answer resource subscriptions
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params struct {
+		RequestID json.RawMessage `json:"requestId"`
+		URI       string          `json:"uri"`
+	} `json:"params"`
+}
+
+// cancelled reports whether the message is notifications/cancelled, which a
+// client sends to stop one of its requests
+func (m message) cancelled() bool {
+	return m.Method == "notifications/cancelled" && len(m.Params.RequestID) > 0
+}
+
+// rpcError is the JSON-RPC error response to request id
+func rpcError(id json.RawMessage, code int, message string) mcp.JSONRPCError {
+	res := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION, ID: id}
+	res.Error.Code = code
+	res.Error.Message = message
+	return res
+}
+
+// calls tracks the requests in progress of every MCP session, as mcp-go
+// doesn't act on notifications/cancelled itself. A cancelled request that
+// is waiting for the browser, held by another request, stops only once it
+// gets it; requests release the browser while they wait for the user.
+type calls struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func newCalls() *calls {
+	return &calls{cancels: map[string]context.CancelFunc{}}
+}
+
+func callKey(session string, id json.RawMessage) string {
+	return session + " " + string(id)
+}
+
+// start returns the context of request id of session, which is cancelled
+// by cancel or once done is called
+func (c *calls) start(ctx context.Context, session string, id json.RawMessage) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := callKey(session, id)
+
+	c.mu.Lock()
+	c.cancels[key] = cancel
+	c.mu.Unlock()
+
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.cancels, key)
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel stops request id of session if it is still in progress
+func (c *calls) cancel(session string, id json.RawMessage) {
+	c.mu.Lock()
+	cancel := c.cancels[callKey(session, id)]
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// StartMCP serves the MCP server m over SSE for remote clients, behind the
+// same API key and CORS checks as the REST API. Clients connect to /sse and
+// post messages to /message. All clients share the one browser. Resource
+// subscriptions go to subs.
+func (s *HTTPServer) StartMCP(m *server.MCPServer, subs Subscriber) error {
+	sse := server.NewSSEServer(m, server.WithBaseURL(s.baseURL))
+	handler := s.corsMiddleware(s.authMiddleware(
+		subscribeMiddleware(sse, subs, cancelMiddleware(sse.CompleteMessagePath(), sse))))
+
+	addr := fmt.Sprintf(":%d", s.port)
+	if len(s.apiKeys) == 0 {
+		fmt.Fprintln(os.Stderr, "Warning: no API_KEYS set, anyone who can reach this server can control the browser")
+	}
+	fmt.Fprintf(os.Stderr, "Serving MCP on http://localhost%s%s\n", addr, sse.CompleteSsePath())
+	return http.ListenAndServe(addr, handler)
+}
+
+// cancelMiddleware runs each request posted to messagePath under a context
+// that the client's notifications/cancelled for it cancels
+func cancelMiddleware(messagePath string, next http.Handler) http.Handler {
+	inFlight := newCalls()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != messagePath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Messages that don't parse are left for the SSE server to reject
+		var msg message
+		if json.Unmarshal(body, &msg) == nil {
+			session := r.URL.Query().Get("sessionId")
+			switch {
+			case msg.cancelled():
+				inFlight.cancel(session, msg.Params.RequestID)
+			case len(msg.ID) > 0:
+				ctx, done := inFlight.start(r.Context(), session, msg.ID)
+				defer done()
+				r = r.WithContext(ctx)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
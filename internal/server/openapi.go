@@ -0,0 +1,251 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/afalcongonzalez/surfmate.io/internal/tools"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIDescription introduces the API in the OpenAPI document
+const openAPIDescription = "Control a browser for web automation. The browser window is visible so users can solve captchas and complete logins. If the browser crashed or disconnected it is restarted on the next request, reopening the last page, and the X-Browser-Recovered response header says so. When the server has API keys, requests without one get 401."
+
+// errorBody is the response to a failed request
+type errorBody struct {
+	Error string `json:"error"`
+	*browser.ElementError
+}
+
+// refusalBody is the response to a refused navigation or action
+type refusalBody struct {
+	Error  string `json:"error"`
+	URL    string `json:"url,omitempty" description:"The blocked URL"`
+	Action string `json:"action,omitempty" description:"The action the user did not approve"`
+	Reason string `json:"reason" description:"Why the URL was blocked, or denied when the user refused the action"`
+}
+
+type openAPIDoc struct {
+	OpenAPI    string                          `yaml:"openapi"`
+	Info       map[string]string               `yaml:"info"`
+	Servers    []map[string]string             `yaml:"servers"`
+	Security   []map[string][]string           `yaml:"security"`
+	Paths      map[string]map[string]operation `yaml:"paths"`
+	Components map[string]any                  `yaml:"components"`
+}
+
+type operation struct {
+	OperationID string              `yaml:"operationId"`
+	Description string              `yaml:"description"`
+	Parameters  []parameter         `yaml:"parameters,omitempty"`
+	RequestBody *body               `yaml:"requestBody,omitempty"`
+	Responses   map[string]response `yaml:"responses"`
+}
+
+type parameter struct {
+	Name        string         `yaml:"name"`
+	In          string         `yaml:"in"`
+	Required    bool           `yaml:"required,omitempty"`
+	Deprecated  bool           `yaml:"deprecated,omitempty"`
+	Description string         `yaml:"description,omitempty"`
+	Schema      map[string]any `yaml:"schema,omitempty"`
+	Content     map[string]any `yaml:"content,omitempty"`
+}
+
+type body struct {
+	Required bool           `yaml:"required"`
+	Content  map[string]any `yaml:"content"`
+}
+
+type response struct {
+	Description string         `yaml:"description"`
+	Content     map[string]any `yaml:"content"`
+}
+
+// jsonContent is the content of a JSON request or response with schema
+func jsonContent(schema any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// ref points to a component schema
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// openAPI generates the OpenAPI document from the tool declarations
+func (s *HTTPServer) openAPI() ([]byte, error) {
+	url := s.baseURL
+	if url == "" {
+		url = fmt.Sprintf("http://localhost:%d", s.port)
+	}
+
+	c := schemas{}
+	c["Error"] = c.object(reflect.TypeOf(errorBody{}))
+	c["Refusal"] = c.object(reflect.TypeOf(refusalBody{}))
+
+	paths := map[string]map[string]operation{}
+	for _, t := range tools.Defs() {
+		method, path := t.Route()
+		op := operation{
+			OperationID: t.Name,
+			Description: t.Description,
+			Responses: map[string]response{
+				"200":     {Description: "Success", Content: jsonContent(c.of(reflect.TypeOf(t.Output)))},
+				"400":     {Description: "Invalid arguments", Content: jsonContent(ref("Error"))},
+				"default": {Description: "Error, with element diagnostics when a selector could not be used", Content: jsonContent(ref("Error"))},
+			},
+		}
+		if t.Refusable {
+			op.Responses["403"] = response{
+				Description: "The URL policy blocked the navigation, or the user did not approve the action",
+				Content:     jsonContent(ref("Refusal")),
+			}
+		}
+
+		if method == http.MethodGet {
+			for _, p := range t.Params {
+				param := parameter{Name: p.Name, In: "query", Required: p.Required,
+					Deprecated: p.Deprecated, Description: p.Description}
+				schema := p.Schema()
+				delete(schema, "description")
+				delete(schema, "deprecated")
+				if p.Type == tools.TypeObject || p.Type == tools.TypeArray {
+					param.Content = jsonContent(schema)
+				} else {
+					param.Schema = schema
+				}
+				op.Parameters = append(op.Parameters, param)
+			}
+		} else {
+			properties := map[string]any{}
+			var required []string
+			for _, p := range t.Params {
+				properties[p.Name] = p.Schema()
+				if p.Required {
+					required = append(required, p.Name)
+				}
+			}
+			schema := map[string]any{"type": "object", "properties": properties}
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			op.RequestBody = &body{Required: len(required) > 0, Content: jsonContent(schema)}
+		}
+
+		paths[path] = map[string]operation{strings.ToLower(method): op}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err := enc.Encode(openAPIDoc{
+		OpenAPI: "3.1.0",
+		Info: map[string]string{
+			"title":       "Surfmate.io Browser Automation",
+			"description": openAPIDescription,
+			"version":     "1.0.0",
+		},
+		Servers:  []map[string]string{{"url": url}},
+		Security: []map[string][]string{{"bearerAuth": {}}, {"apiKeyAuth": {}}},
+		Paths:    paths,
+		Components: map[string]any{
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]string{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "One of the server's API_KEYS. Not needed when the server has none.",
+				},
+				"apiKeyAuth": map[string]string{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "X-API-Key",
+					"description": "One of the server's API_KEYS, as an alternative to the bearer token",
+				},
+			},
+			"schemas": c,
+		},
+	})
+	return buf.Bytes(), err
+}
+
+// schemas builds the JSON Schemas of Go types from their JSON field names
+// and description and enum tags. Exported named structs become components.
+type schemas map[string]any
+
+func (c schemas) of(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if !token.IsExported(t.Name()) {
+			return c.object(t)
+		}
+		if _, ok := c[t.Name()]; !ok {
+			c[t.Name()] = nil // stops recursion
+			c[t.Name()] = c.object(t)
+		}
+		return ref(t.Name())
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": c.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// object returns the schema of a struct, with the fields of embedded
+// structs inlined as encoding/json does
+func (c schemas) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	c.fields(t, properties)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func (c schemas) fields(t reflect.Type, properties map[string]any) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			c.fields(embedded, properties)
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		schema := c.of(f.Type)
+		if d := f.Tag.Get("description"); d != "" {
+			schema["description"] = d
+		}
+		if e := f.Tag.Get("enum"); e != "" {
+			schema["enum"] = strings.Split(e, ",")
+		}
+		properties[name] = schema
+	}
+}
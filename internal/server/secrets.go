@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/secrets"
+)
+
+// maskingWriter holds back JSON and text responses so secret values can be
+// masked before they are sent. Other responses, such as images, pass through.
+type maskingWriter struct {
+	http.ResponseWriter
+	store       *secrets.Store
+	status      int
+	buf         bytes.Buffer
+	decided     bool
+	passThrough bool
+}
+
+func (w *maskingWriter) WriteHeader(status int) {
+	w.status = status
+	w.decide()
+}
+
+func (w *maskingWriter) Write(p []byte) (int, error) {
+	w.decide()
+	if w.passThrough {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// decide picks buffering or passing through by the response content type,
+// which handlers set before writing, and masks the recovery notice header
+func (w *maskingWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	if notice := w.Header().Get("X-Browser-Recovered"); notice != "" {
+		w.Header().Set("X-Browser-Recovered", w.store.Mask(notice))
+	}
+	ct := w.Header().Get("Content-Type")
+	if !strings.HasPrefix(ct, "application/json") && !strings.HasPrefix(ct, "text/") {
+		w.passThrough = true
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+	}
+}
+
+// secretsMiddleware replaces secret values in responses with their
+// {{secret:name}} references, so page text, URLs and errors never show them
+func (s *HTTPServer) secretsMiddleware(next http.Handler) http.Handler {
+	store := s.mgr.Secrets()
+	if store.Empty() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw := &maskingWriter{ResponseWriter: w, store: store}
+		next.ServeHTTP(mw, r)
+		mw.decide()
+		if mw.passThrough {
+			return
+		}
+
+		body := mw.buf.Bytes()
+		if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			body = maskJSON(store, body)
+		} else {
+			body = []byte(store.Mask(string(body)))
+		}
+		w.Header().Del("Content-Length")
+		if mw.status != 0 {
+			w.WriteHeader(mw.status)
+		}
+		w.Write(body)
+	})
+}
+
+// binaryFields are the response fields holding base64 encoded files. They
+// are never masked, since a secret can match part of the encoding and
+// replacing it would corrupt the file.
+var binaryFields = map[string]bool{"image": true, "pdf": true}
+
+// jsonLevel is an object or array maskJSON is inside
+type jsonLevel struct {
+	object bool
+	key    string // key of the value being read, in an object
+	hasKey bool
+}
+
+// maskJSON masks secrets in the strings of a JSON body, leaving binaryFields
+// and the rest of the body as they are. A body that is not valid JSON is
+// masked as raw bytes.
+func maskJSON(store *secrets.Store, body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	var out bytes.Buffer
+	var copied int64 // body[:copied] has been written to out
+	var stack []*jsonLevel
+
+	// valueDone readies an object for its next key
+	valueDone := func() {
+		if len(stack) > 0 {
+			stack[len(stack)-1].hasKey = false
+		}
+	}
+
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return store.MaskJSON(body)
+		}
+		end := dec.InputOffset()
+
+		var top *jsonLevel
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		isKey := top != nil && top.object && !top.hasKey
+
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				stack = append(stack, &jsonLevel{object: t == '{'})
+			default:
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+		case string:
+			if isKey {
+				top.key, top.hasKey = t, true
+			} else {
+				valueDone()
+			}
+			if !isKey && top != nil && top.object && binaryFields[top.key] {
+				continue
+			}
+			masked := store.Mask(t)
+			if masked == t {
+				continue
+			}
+			// start may include the separator before the string
+			quote := start + int64(bytes.IndexByte(body[start:end], '"'))
+			encoded, err := json.Marshal(masked)
+			if err != nil {
+				return store.MaskJSON(body)
+			}
+			out.Write(body[copied:quote])
+			out.Write(encoded)
+			copied = end
+		default:
+			valueDone()
+		}
+	}
+	out.Write(body[copied:])
+	return out.Bytes()
+}
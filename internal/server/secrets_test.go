@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/secrets"
+)
+
+func TestMaskJSON(t *testing.T) {
+	store := secrets.New(map[string]string{"pw": "AAAABBBBCCCC"})
+
+	// image holds bytes whose base64 encoding contains the secret
+	image, err := base64.StdEncoding.DecodeString("xxxxAAAABBBBCCCCyyyy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(image)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "string value", body: `{"text":"pw is AAAABBBBCCCC"}` + "\n", want: `{"text":"pw is {{secret:pw}}"}` + "\n"},
+		{name: "order and spacing kept", body: `{"z": 1, "a": "AAAABBBBCCCC", "m": true}`, want: `{"z": 1, "a": "{{secret:pw}}", "m": true}`},
+		{name: "nested", body: `{"results":[{"url":"https://x/?p=AAAABBBBCCCC"},"AAAABBBBCCCC"]}`, want: `{"results":[{"url":"https://x/?p={{secret:pw}}"},"{{secret:pw}}"]}`},
+		{name: "key", body: `{"AAAABBBBCCCC":null}`, want: `{"{{secret:pw}}":null}`},
+		{name: "image", body: `{"image":"` + encoded + `","text":"AAAABBBBCCCC"}`, want: `{"image":"` + encoded + `","text":"{{secret:pw}}"}`},
+		{name: "pdf", body: `{"bytes":20,"pdf":"` + encoded + `"}`, want: `{"bytes":20,"pdf":"` + encoded + `"}`},
+		{name: "image in batch results", body: `{"results":[{"ok":true,"image":"` + encoded + `"}]}`, want: `{"results":[{"ok":true,"image":"` + encoded + `"}]}`},
+		{name: "value after image", body: `{"image":"` + encoded + `","title":"AAAABBBBCCCC"}`, want: `{"image":"` + encoded + `","title":"{{secret:pw}}"}`},
+		{name: "image as a value is masked", body: `["image","AAAABBBBCCCC"]`, want: `["image","{{secret:pw}}"]`},
+		{name: "invalid JSON", body: `{"text":"AAAABBBBCCCC"`, want: `{"text":"{{secret:pw}}"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := maskJSON(store, []byte(tt.body))
+			if string(got) != tt.want {
+				t.Errorf("maskJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskJSONKeepsImage(t *testing.T) {
+	store := secrets.New(map[string]string{"pw": "AAAABBBBCCCC"})
+	image, err := base64.StdEncoding.DecodeString("xxxxAAAABBBBCCCCyyyy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(screenshotBody{Image: image, Title: "AAAABBBBCCCC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got screenshotBody
+	if err := json.Unmarshal(maskJSON(store, body), &got); err != nil {
+		t.Fatalf("masked body is not valid JSON: %v", err)
+	}
+	if !bytes.Equal(got.Image, image) {
+		t.Error("masking changed the image")
+	}
+	if got.Title != "{{secret:pw}}" {
+		t.Errorf("title = %q, want it masked", got.Title)
+	}
+}
+
+// screenshotBody has the fields of a screenshot response
+type screenshotBody struct {
+	Image []byte `json:"image"`
+	Title string `json:"title"`
+}
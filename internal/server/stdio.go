@@ -0,0 +1,202 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxQueued is how many stdio requests may wait for the one running
+const maxQueued = 100
+
+// codeBusy is the JSON-RPC error code of a request refused because
+// maxQueued requests are already waiting
+const codeBusy = -32000
+
+// stdioSession is the single MCP session of a stdio server
+type stdioSession struct {
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+}
+
+func (s *stdioSession) SessionID() string { return "stdio" }
+func (s *stdioSession) Initialize()       { s.initialized.Store(true) }
+func (s *stdioSession) Initialized() bool { return s.initialized.Load() }
+
+func (s *stdioSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// ServeStdio serves m over stdin and stdout until stdin closes and the
+// requests read by then are answered. Unlike server.ServeStdio it keeps
+// reading while a request runs, so notifications/cancelled can stop it.
+// Requests still run one at a time, in order; once maxQueued are waiting,
+// further ones are refused until the queue drains. Resource subscriptions
+// go to subs. Signals are left to the caller, which closes the browser.
+func ServeStdio(m *server.MCPServer, subs Subscriber) error {
+	return serveStdio(context.Background(), m, subs, os.Stdin, os.Stdout)
+}
+
+func serveStdio(ctx context.Context, m *server.MCPServer, subs Subscriber, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	session := &stdioSession{notifications: make(chan mcp.JSONRPCNotification, 100)}
+	if err := m.RegisterSession(ctx, session); err != nil {
+		return fmt.Errorf("register session: %w", err)
+	}
+	defer m.UnregisterSession(session.SessionID())
+	ctx = m.WithContext(ctx, session)
+
+	// Responses and notifications share stdout, one line each
+	var mu sync.Mutex
+	write := func(v any) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode MCP message: %v\n", err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, "%s\n", data)
+	}
+	go func() {
+		for {
+			select {
+			case n := <-session.notifications:
+				write(n)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Requests are queued with their context, so one cancelled while it
+	// waits is refused as soon as it runs
+	type job struct {
+		ctx  context.Context
+		raw  json.RawMessage
+		id   json.RawMessage
+		done func()
+	}
+	queue := make(chan job, maxQueued)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := range queue {
+			if res := handleMessage(j.ctx, m, j.raw, j.id); res != nil {
+				write(res)
+			}
+			j.done()
+		}
+	}()
+
+	inFlight := newCalls()
+	err := readLines(ctx, in, func(line string) {
+		j := job{ctx: ctx, raw: json.RawMessage(line), done: func() {}}
+
+		// Lines that don't parse are left for HandleMessage to reject
+		var msg message
+		if json.Unmarshal(j.raw, &msg) == nil {
+			if res, ok := subscription(subs, session.SessionID(), msg); ok {
+				write(res)
+				return
+			}
+			switch {
+			case msg.cancelled():
+				inFlight.cancel(session.SessionID(), msg.Params.RequestID)
+				return
+			case len(msg.ID) > 0:
+				j.id = msg.ID
+				j.ctx, j.done = inFlight.start(ctx, session.SessionID(), msg.ID)
+			}
+		}
+
+		// Never block here, or cancellations that would free the queue
+		// couldn't be read
+		select {
+		case queue <- j:
+		default:
+			j.done()
+			if len(j.id) > 0 {
+				write(rpcError(j.id, codeBusy, fmt.Sprintf("server busy: %d requests are already waiting, try again later", maxQueued)))
+				return
+			}
+			// Notifications don't touch the browser, so one that doesn't
+			// fit is handled right away rather than lost
+			if res := handleMessage(ctx, m, j.raw, nil); res != nil {
+				write(res)
+			}
+		}
+	})
+
+	// Requests sent before stdin closed still run, as a client may close it
+	// right after its last request
+	close(queue)
+	wg.Wait()
+	return err
+}
+
+// handleMessage handles raw with m, answering request id with an internal
+// error instead of crashing the server when a handler panics
+func handleMessage(ctx context.Context, m *server.MCPServer, raw, id json.RawMessage) (res mcp.JSONRPCMessage) {
+	defer func() {
+		if p := recover(); p != nil {
+			fmt.Fprintf(os.Stderr, "Panic handling MCP message: %v\n%s", p, debug.Stack())
+			res = nil
+			if len(id) > 0 {
+				res = rpcError(id, mcp.INTERNAL_ERROR, fmt.Sprintf("internal error: %v", p))
+			}
+		}
+	}()
+	return m.HandleMessage(ctx, raw)
+}
+
+// readLines calls handle with every non-blank line of in until it ends or
+// ctx is done
+func readLines(ctx context.Context, in io.Reader, handle func(line string)) error {
+	lines := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadString('\n')
+			if strings.TrimSpace(line) != "" {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case line := <-lines:
+			handle(line)
+		case err := <-errs:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// stdioResponse is a JSON-RPC response read from serveStdio
+type stdioResponse struct {
+	ID    json.RawMessage `json:"id"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// stdioClient talks to serveStdio over pipes
+type stdioClient struct {
+	t         *testing.T
+	in        *io.PipeWriter
+	responses chan stdioResponse
+	done      chan error
+}
+
+// startStdio serves a test MCP server over stdio. Its block tool signals
+// started and then runs until its request is cancelled. Like the browser
+// tools it fails at once when its request was cancelled before it ran.
+func startStdio(t *testing.T, started chan<- struct{}) *stdioClient {
+	t.Helper()
+	s := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+	s.AddTool(mcp.NewTool("block"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &stdioClient{t: t, in: inW, responses: make(chan stdioResponse, 2*maxQueued), done: make(chan error, 1)}
+	go func() {
+		c.done <- serveStdio(context.Background(), s, nil, inR, outW)
+		outW.Close()
+	}()
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			var res stdioResponse
+			if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+				t.Errorf("invalid response %s: %v", scanner.Text(), err)
+				continue
+			}
+			c.responses <- res
+		}
+		close(c.responses)
+	}()
+	t.Cleanup(func() { inW.Close() })
+	return c
+}
+
+// send writes one JSON-RPC message, failing if the server stops reading
+func (c *stdioClient) send(method string, id any, params any) {
+	c.t.Helper()
+	msg := map[string]any{"jsonrpc": "2.0", "method": method}
+	if id != nil {
+		msg["id"] = id
+	}
+	if params != nil {
+		msg["params"] = params
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := c.in.Write(append(data, '\n'))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			c.t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("server stopped reading at %s %v", method, id)
+	}
+}
+
+// next returns the next response
+func (c *stdioClient) next() stdioResponse {
+	c.t.Helper()
+	select {
+	case res, ok := <-c.responses:
+		if !ok {
+			c.t.Fatal("server closed stdout")
+		}
+		return res
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("no response")
+	}
+	return stdioResponse{}
+}
+
+// wait waits for started, failing if it isn't signalled in time
+func wait(t *testing.T, started <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not start")
+	}
+}
+
+func TestServeStdioOrder(t *testing.T) {
+	c := startStdio(t, make(chan struct{}, 1))
+	for i := 1; i <= 20; i++ {
+		if i%2 == 0 {
+			c.send("tools/list", i, nil)
+		} else {
+			c.send("ping", i, nil)
+		}
+	}
+	c.send("notifications/initialized", nil, nil)
+	c.in.Close()
+
+	// Requests sent before stdin closed are all answered, in order
+	for i := 1; i <= 20; i++ {
+		res := c.next()
+		if string(res.ID) != fmt.Sprint(i) {
+			t.Fatalf("response %d has id %s", i, res.ID)
+		}
+		if res.Error != nil {
+			t.Errorf("request %d failed: %s", i, res.Error.Message)
+		}
+	}
+	if err := <-c.done; err != nil {
+		t.Errorf("serveStdio() = %v", err)
+	}
+	if res, ok := <-c.responses; ok {
+		t.Errorf("unexpected response %s", res.ID)
+	}
+}
+
+func TestServeStdioCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	c := startStdio(t, started)
+
+	c.send("tools/call", 1, map[string]any{"name": "block"})
+	wait(t, started)
+	c.send("ping", 2, nil)
+	c.send("notifications/cancelled", nil, map[string]any{"requestId": 1})
+
+	if res := c.next(); string(res.ID) != "1" {
+		t.Fatalf("first response has id %s, want the cancelled request 1", res.ID)
+	}
+	if res := c.next(); string(res.ID) != "2" || res.Error != nil {
+		t.Fatalf("second response = %+v, want ping 2 answered", res)
+	}
+}
+
+func TestServeStdioCancelQueued(t *testing.T) {
+	started := make(chan struct{}, 2)
+	c := startStdio(t, started)
+
+	// The second call is cancelled while it waits, so it never starts
+	c.send("tools/call", 1, map[string]any{"name": "block"})
+	wait(t, started)
+	c.send("tools/call", 2, map[string]any{"name": "block"})
+	c.send("notifications/cancelled", nil, map[string]any{"requestId": 2})
+	c.send("notifications/cancelled", nil, map[string]any{"requestId": 1})
+
+	for _, id := range []string{"1", "2"} {
+		if res := c.next(); string(res.ID) != id {
+			t.Fatalf("response has id %s, want %s", res.ID, id)
+		}
+	}
+	select {
+	case <-started:
+		t.Error("cancelled request 2 ran")
+	default:
+	}
+}
+
+func TestServeStdioFullQueue(t *testing.T) {
+	started := make(chan struct{}, 1)
+	c := startStdio(t, started)
+
+	c.send("tools/call", 0, map[string]any{"name": "block"})
+	wait(t, started)
+	for i := 1; i <= maxQueued; i++ {
+		c.send("ping", i, nil)
+	}
+
+	// The queue is full: the next request is refused at once, and the
+	// reader still reads the cancellation that frees it
+	c.send("ping", "extra", nil)
+	res := c.next()
+	if string(res.ID) != `"extra"` || res.Error == nil || res.Error.Code != codeBusy {
+		t.Fatalf("response = %+v, want busy error for extra", res)
+	}
+	c.send("notifications/cancelled", nil, map[string]any{"requestId": 0})
+
+	for i := 0; i <= maxQueued; i++ {
+		if res := c.next(); string(res.ID) != fmt.Sprint(i) {
+			t.Fatalf("response has id %s, want %d", res.ID, i)
+		}
+	}
+
+	// Once the queue has drained, requests are accepted again
+	c.send("ping", "later", nil)
+	if res := c.next(); string(res.ID) != `"later"` || res.Error != nil {
+		t.Fatalf("response = %+v, want ping answered", res)
+	}
+}
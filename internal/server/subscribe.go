@@ -0,0 +1,81 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Subscriber keeps the resource subscriptions of MCP sessions. mcp-go
+// answers resources/subscribe and resources/unsubscribe with method not
+// found, so the transports pass them on.
+type Subscriber interface {
+	Subscribe(session, uri string) error
+	Unsubscribe(session, uri string) error
+}
+
+// subscription answers msg for session when it is a resources/subscribe or
+// resources/unsubscribe request, reporting whether it was one
+func subscription(subs Subscriber, session string, msg message) (mcp.JSONRPCMessage, bool) {
+	if subs == nil || len(msg.ID) == 0 {
+		return nil, false
+	}
+
+	var err error
+	switch msg.Method {
+	case "resources/subscribe":
+		err = subs.Subscribe(session, msg.Params.URI)
+	case "resources/unsubscribe":
+		err = subs.Unsubscribe(session, msg.Params.URI)
+	default:
+		return nil, false
+	}
+
+	if err != nil {
+		return rpcError(msg.ID, mcp.INVALID_PARAMS, err.Error()), true
+	}
+	return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: msg.ID, Result: mcp.EmptyResult{}}, true
+}
+
+// subscribeMiddleware answers the subscription requests posted to the
+// message path of sse, sending the response over the client's event stream
+// as the SSE server does
+func subscribeMiddleware(sse *server.SSEServer, subs Subscriber, next http.Handler) http.Handler {
+	messagePath := sse.CompleteMessagePath()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != messagePath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var msg message
+		if json.Unmarshal(body, &msg) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		session := r.URL.Query().Get("sessionId")
+		res, ok := subscription(subs, session, msg)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := sse.SendEventToSession(session, res); err != nil {
+			errorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(res)
+	})
+}
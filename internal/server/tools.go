@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/tools"
+)
+
+// handleTool serves a tool. GET routes take the arguments from the query
+// string, others from a JSON object body, which may be left out when no
+// argument is required.
+func (s *HTTPServer) handleTool(t tools.Def) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw := map[string]any{}
+		if r.Method == http.MethodGet {
+			var err error
+			if raw, err = queryArgs(t.Params, r.URL.Query()); err != nil {
+				managerErrorResponse(w, err)
+				return
+			}
+		} else if err := json.NewDecoder(r.Body).Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+			errorResponse(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		var res *tools.Result
+		err := s.record(t.Name, raw, func() error {
+			args, err := tools.Validate(t.Params, raw)
+			if err != nil {
+				return err
+			}
+			res, err = t.Run(r.Context(), s.mgr, args)
+			return err
+		})
+		if err != nil {
+			managerErrorResponse(w, err)
+			return
+		}
+		jsonResponse(w, res.Data)
+	}
+}
+
+// record runs call, adding it to the trace when tracing is enabled
+func (s *HTTPServer) record(name string, args map[string]any, call func() error) error {
+	if s.recorder == nil {
+		return call()
+	}
+
+	var err error
+	s.recorder.Record(name, args, func() string {
+		if err = call(); err != nil {
+			return err.Error()
+		}
+		return ""
+	})
+	return err
+}
+
+// queryArgs converts query parameters to the types params declare. Objects
+// and arrays are given as JSON. Unknown parameters are ignored.
+func queryArgs(params []tools.Param, q url.Values) (map[string]any, error) {
+	raw := map[string]any{}
+	for name := range q {
+		v := q.Get(name)
+		i := slices.IndexFunc(params, func(p tools.Param) bool { return p.Name == name })
+		if i < 0 {
+			continue
+		}
+
+		var err error
+		problem := "must be a number"
+		switch params[i].Type {
+		case tools.TypeBoolean:
+			raw[name], err = strconv.ParseBool(v)
+			problem = "must be true or false"
+		case tools.TypeInteger, tools.TypeNumber:
+			raw[name], err = strconv.ParseFloat(v, 64)
+		case tools.TypeObject, tools.TypeArray:
+			var decoded any
+			err = json.Unmarshal([]byte(v), &decoded)
+			raw[name] = decoded
+			problem = "must be JSON"
+		default:
+			raw[name] = v
+		}
+		if err != nil {
+			return nil, &tools.ArgError{Message: fmt.Sprintf("%s %s", name, problem)}
+		}
+	}
+	return raw, nil
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// actionSchema is the JSON Schema of one run_actions step
+var actionSchema = map[string]any{
+	"type":        TypeObject,
+	"description": "One step. Each action reads only the fields it uses",
+	"required":    []string{"action"},
+	"properties": map[string]any{
+		"action": map[string]any{
+			"type": TypeString,
+			"enum": []string{browser.ActionNavigate, browser.ActionClick, browser.ActionType, browser.ActionWaitFor, browser.ActionExtract, browser.ActionScreenshot},
+		},
+		"url":               map[string]any{"type": TypeString, "description": "URL for navigate"},
+		"selector":          map[string]any{"type": TypeString, "description": "Element for click, type, wait_for, extract and screenshot"},
+		"text":              map[string]any{"type": TypeString, "description": "Text for type, which may use {{secret:name}} references"},
+		"submit":            map[string]any{"type": TypeBoolean, "description": "Press Enter after type"},
+		"multiple":          map[string]any{"type": TypeBoolean, "description": "Extract from all matching elements"},
+		"timeout_ms":        map[string]any{"type": TypeInteger, "description": "How long wait_for waits for the element to be visible"},
+		"full_page":         map[string]any{"type": TypeBoolean},
+		"format":            map[string]any{"type": TypeString, "enum": []string{browser.FormatPNG, browser.FormatJPEG, browser.FormatWebP}},
+		"quality":           map[string]any{"type": TypeInteger, "minimum": 1, "maximum": 100},
+		"max_width":         map[string]any{"type": TypeInteger},
+		"max_height":        map[string]any{"type": TypeInteger},
+		"continue_on_error": map[string]any{"type": TypeBoolean, "default": false, "description": "Run the following steps even if this one fails"},
+	},
+}
+
+// batchResult is the HTTP response of run_actions
+type batchResult struct {
+	Completed bool                   `json:"completed" description:"Whether every step ran and succeeded or was allowed to fail"`
+	Error     string                 `json:"error,omitempty" description:"Why the batch stopped before its steps finished, such as a cancelled request"`
+	Results   []browser.ActionResult `json:"results"`
+}
+
+// RunActionsTool declares the run_actions tool
+func RunActionsTool() Def {
+	return Def{
+		Name:        "run_actions",
+		Description: "Run a sequence of actions in one call and return a result per step. A failed step stops the sequence unless it sets continue_on_error. Screenshots are returned as images in step order.",
+		Params: []Param{
+			{Name: "actions", Type: TypeArray, Required: true, Items: actionSchema,
+				Description: "Ordered steps. Each has an action and the fields it uses: navigate (url), click (selector), type (selector, text, submit), wait_for (selector, timeout_ms), extract (selector, multiple), screenshot (selector, full_page, format, quality, max_width, max_height). Selectors accept " + browser.LocatorHelp},
+		},
+		Path:   "/batch",
+		Output: batchResult{},
+		Run:    runActions,
+	}
+}
+
+func runActions(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+	var actions []browser.Action
+	if err := args.Decode("actions", &actions); err != nil {
+		return nil, err
+	}
+	if err := browser.ValidateActions(actions); err != nil {
+		return nil, &ArgError{Message: err.Error()}
+	}
+
+	results, err := mgr.RunActions(ctx, actions, func(step int, a browser.Action) {
+		reportProgress(ctx, float64(step-1), float64(len(actions)), fmt.Sprintf("Step %d of %d: %s", step, len(actions), a.Action))
+	})
+	if results == nil {
+		return nil, fmt.Errorf("run_actions failed: %w", err)
+	}
+
+	// Screenshots are returned as image content rather than inline base64
+	var images []mcp.Content
+	summary := make([]browser.ActionResult, len(results))
+	for i, r := range results {
+		if r.Image != nil {
+			images = append(images, mcp.NewImageContent(base64.StdEncoding.EncodeToString(r.Image), r.MIMEType))
+			r.Image = nil
+		}
+		summary[i] = r
+	}
+
+	text, jerr := json.MarshalIndent(summary, "", "  ")
+	if jerr != nil {
+		return nil, fmt.Errorf("run_actions failed: %w", jerr)
+	}
+	content := []mcp.Content{mcp.NewTextContent(string(text))}
+
+	// The steps run before an error such as a cancellation are still
+	// returned, with the error
+	out := batchResult{Completed: err == nil && completed(actions, results), Results: results}
+	if err != nil {
+		out.Error = err.Error()
+		content = append(content, mcp.NewTextContent(fmt.Sprintf("run_actions stopped after %d of %d steps: %v", len(results), len(actions), err)))
+	}
+	return &Result{Content: append(content, images...), Data: out}, nil
+}
+
+// completed reports whether every action ran and either succeeded or was
+// allowed to fail with continue_on_error
+func completed(actions []browser.Action, results []browser.ActionResult) bool {
+	if len(results) != len(actions) {
+		return false
+	}
+	for i, r := range results {
+		if !r.OK && !actions[i].ContinueOnError {
+			return false
+		}
+	}
+	return true
+}
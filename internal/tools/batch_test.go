@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+)
+
+func TestCompleted(t *testing.T) {
+	step := browser.Action{Action: browser.ActionClick, Selector: "#a"}
+	optional := browser.Action{Action: browser.ActionClick, Selector: "#a", ContinueOnError: true}
+	ok := browser.ActionResult{OK: true}
+	failed := browser.ActionResult{Error: "element not found"}
+
+	tests := []struct {
+		name    string
+		actions []browser.Action
+		results []browser.ActionResult
+		want    bool
+	}{
+		{name: "all succeeded", actions: []browser.Action{step, step}, results: []browser.ActionResult{ok, ok}, want: true},
+		{name: "stopped early", actions: []browser.Action{step, step}, results: []browser.ActionResult{failed}, want: false},
+		{name: "last step failed", actions: []browser.Action{step, step}, results: []browser.ActionResult{ok, failed}, want: false},
+		{name: "last step allowed to fail", actions: []browser.Action{step, optional}, results: []browser.ActionResult{ok, failed}, want: true},
+		{name: "middle step allowed to fail", actions: []browser.Action{optional, step}, results: []browser.ActionResult{failed, ok}, want: true},
+		{name: "no steps run", actions: []browser.Action{step}, results: []browser.ActionResult{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := completed(tt.actions, tt.results); got != tt.want {
+				t.Errorf("completed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+)
+
+// changeParams are the tool arguments controlling post-action change reports
+var changeParams = []Param{
+	{Name: "report_changes", Type: TypeBoolean, Default: false,
+		Description: "Wait for the page to settle and report what changed: URL, title, new dialogs, newly visible elements and captcha status"},
+	{Name: "settle_ms", Type: TypeInteger, Default: int(browser.DefaultSettle / time.Millisecond), Min: bound(1),
+		Description: "Maximum time in milliseconds to wait for the page to settle when report_changes is set"},
+}
+
+// runObserved runs a click or type action, returning a summary of the page
+// changes it caused when the arguments ask for one
+func runObserved(mgr *browser.Manager, args Args, a browser.Action) (*browser.StateChange, error) {
+	if args.Bool("report_changes") {
+		return mgr.Observe(time.Duration(args.Int("settle_ms"))*time.Millisecond, a)
+	}
+	if a.Action == browser.ActionType {
+		return nil, mgr.Type(a.Selector, a.Text, a.Submit)
+	}
+	return nil, mgr.Click(a.Selector)
+}
+
+// actionError reports err from runObserved as a failure of the named
+// action, unless the action ran and only reading its changes failed
+func actionError(name string, err error) error {
+	var observeErr *browser.ObserveError
+	if errors.As(err, &observeErr) {
+		return err
+	}
+	return fmt.Errorf("%s failed: %w", name, err)
+}
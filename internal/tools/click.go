@@ -5,33 +5,38 @@ import (
 	"fmt"
 
 	"github.com/afalcongonzalez/surfmate.io/internal/browser"
-	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// ClickHandler handles the click tool
-func ClickHandler(mgr *browser.Manager) func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		selector, ok := req.Params.Arguments["selector"].(string)
-		if !ok || selector == "" {
-			return mcp.NewToolResultError("selector parameter is required"), nil
-		}
+// clickResult is the HTTP response of click
+type clickResult struct {
+	Status   string               `json:"status" enum:"clicked"`
+	Selector string               `json:"selector"`
+	Changes  *browser.StateChange `json:"changes,omitempty" description:"Present when report_changes is set"`
+}
 
-		if err := mgr.Click(selector); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("click failed: %v", err)), nil
-		}
+// ClickTool declares the click tool
+func ClickTool() Def {
+	return Def{
+		Name:        "click",
+		Description: "Click an element on the page by CSS selector or text, role, label, placeholder or XPath locator. Sensitive clicks such as Delete or payment buttons may wait for the user to approve them; a refusal is returned as an error.",
+		Params: append([]Param{
+			{Name: "selector", Type: TypeString, Required: true,
+				Description: "Element to click. " + browser.LocatorHelp},
+		}, changeParams...),
+		Output:    clickResult{},
+		Refusable: true,
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			selector := args.String("selector")
+			change, err := runObserved(mgr, args, browser.Action{Action: browser.ActionClick, Selector: selector})
+			if err != nil {
+				return nil, actionError("click", err)
+			}
 
-		return mcp.NewToolResultText(fmt.Sprintf("Clicked element: %s", selector)), nil
+			text := fmt.Sprintf("Clicked element: %s", selector)
+			if change != nil {
+				text += "\n" + change.String()
+			}
+			return textResult(text, clickResult{Status: "clicked", Selector: selector, Changes: change}), nil
+		},
 	}
 }
-
-// ClickTool returns the tool definition for click
-func ClickTool() mcp.Tool {
-	return mcp.NewTool(
-		"click",
-		mcp.WithDescription("Click an element on the page by CSS selector or XPath."),
-		mcp.WithString("selector",
-			mcp.Required(),
-			mcp.Description("CSS selector or XPath to the element to click"),
-		),
-	)
-}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+)
+
+// EmulateDeviceTool declares the emulate_device tool
+func EmulateDeviceTool() Def {
+	return Def{
+		Name:        "emulate_device",
+		Description: "Emulate a device or set the viewport, and emulate dark/light color scheme and reduced motion. Settings not given are kept. Navigate again for a new user agent to reach server-rendered pages.",
+		Params: []Param{
+			{Name: "device", Type: TypeString, Enum: browser.DeviceNames(),
+				Description: "Device preset setting size, scale factor, mobile, touch and user agent. Use default to restore the launch viewport"},
+			{Name: "landscape", Type: TypeBoolean, Default: false,
+				Description: "Rotate the device preset to landscape"},
+			{Name: "width", Type: TypeInteger, Min: bound(1),
+				Description: "Viewport width in CSS pixels, overriding the preset"},
+			{Name: "height", Type: TypeInteger, Min: bound(1),
+				Description: "Viewport height in CSS pixels, overriding the preset"},
+			{Name: "device_scale_factor", Type: TypeNumber, Min: bound(0),
+				Description: "Device pixel ratio, 0 for the real one"},
+			{Name: "mobile", Type: TypeBoolean,
+				Description: "Emulate a mobile browser, honouring the page's meta viewport"},
+			{Name: "touch", Type: TypeBoolean,
+				Description: "Emulate a touch screen"},
+			{Name: "user_agent", Type: TypeString,
+				Description: "User agent string, empty for the browser's own"},
+			{Name: "color_scheme", Type: TypeString, Enum: []string{"", browser.ColorSchemeLight, browser.ColorSchemeDark},
+				Description: "prefers-color-scheme to emulate, empty for the system setting"},
+			{Name: "reduced_motion", Type: TypeBoolean,
+				Description: "Emulate prefers-reduced-motion: reduce"},
+
+			// Region settings moved to set_emulation
+			geolocationParam,
+			{Name: "timezone", Type: TypeString, Deprecated: true,
+				Description: "Deprecated: use set_emulation"},
+			{Name: "locale", Type: TypeString, Deprecated: true,
+				Description: "Deprecated: use set_emulation"},
+		},
+		Path:   "/emulate",
+		Output: browser.Emulation{},
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			// Settings not given stay nil and are kept
+			var u browser.EmulationUpdate
+			if err := args.Into(&u); err != nil {
+				return nil, err
+			}
+
+			e, err := mgr.Emulate(u)
+			if err != nil {
+				return nil, fmt.Errorf("emulation failed: %w", err)
+			}
+			return textResult("Emulation applied.\n"+e.String(), e), nil
+		},
+	}
+}
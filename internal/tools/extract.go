@@ -6,51 +6,41 @@ import (
 	"strings"
 
 	"github.com/afalcongonzalez/surfmate.io/internal/browser"
-	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// ExtractTextHandler handles the extract_text tool
-func ExtractTextHandler(mgr *browser.Manager) func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		selector, ok := req.Params.Arguments["selector"].(string)
-		if !ok || selector == "" {
-			return mcp.NewToolResultError("selector parameter is required"), nil
-		}
-
-		multiple := false
-		if m, ok := req.Params.Arguments["multiple"].(bool); ok {
-			multiple = m
-		}
-
-		texts, err := mgr.ExtractText(selector, multiple)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("extract failed: %v", err)), nil
-		}
-
-		if len(texts) == 0 {
-			return mcp.NewToolResultText("No text found for selector: " + selector), nil
-		}
-
-		if multiple {
-			result := fmt.Sprintf("Found %d elements:\n%s", len(texts), strings.Join(texts, "\n---\n"))
-			return mcp.NewToolResultText(result), nil
-		}
-
-		return mcp.NewToolResultText(texts[0]), nil
-	}
+// extractResult is the HTTP response of extract_text
+type extractResult struct {
+	Texts []string `json:"texts"`
 }
 
-// ExtractTextTool returns the tool definition for extract_text
-func ExtractTextTool() mcp.Tool {
-	return mcp.NewTool(
-		"extract_text",
-		mcp.WithDescription("Extract text content from elements matching a selector."),
-		mcp.WithString("selector",
-			mcp.Required(),
-			mcp.Description("CSS selector or XPath to the element(s)"),
-		),
-		mcp.WithBoolean("multiple",
-			mcp.Description("Extract text from all matching elements (default: false, returns first match only)"),
-		),
-	)
+// ExtractTextTool declares the extract_text tool
+func ExtractTextTool() Def {
+	return Def{
+		Name:        "extract_text",
+		Description: "Extract text content from elements matching a selector.",
+		Params: []Param{
+			{Name: "selector", Type: TypeString, Required: true,
+				Description: "Element(s) to extract from. " + browser.LocatorHelp},
+			{Name: "multiple", Type: TypeBoolean, Default: false,
+				Description: "Extract text from all matching elements instead of the first match only"},
+		},
+		Path:   "/extract",
+		Output: extractResult{},
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			selector, multiple := args.String("selector"), args.Bool("multiple")
+			texts, err := mgr.ExtractText(selector, multiple)
+			if err != nil {
+				return nil, fmt.Errorf("extract failed: %w", err)
+			}
+
+			out := extractResult{Texts: texts}
+			switch {
+			case len(texts) == 0:
+				return textResult("No text found for selector: "+selector, extractResult{Texts: []string{}}), nil
+			case multiple:
+				return textResult(fmt.Sprintf("Found %d elements:\n%s", len(texts), strings.Join(texts, "\n---\n")), out), nil
+			}
+			return textResult(texts[0], out), nil
+		},
+	}
 }
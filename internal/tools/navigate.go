@@ -5,42 +5,45 @@ import (
 	"fmt"
 
 	"github.com/afalcongonzalez/surfmate.io/internal/browser"
-	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// NavigateHandler handles the navigate tool
-func NavigateHandler(mgr *browser.Manager) func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		url, ok := req.Params.Arguments["url"].(string)
-		if !ok || url == "" {
-			return mcp.NewToolResultError("url parameter is required"), nil
-		}
-
-		if err := mgr.Navigate(url); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("navigation failed: %v", err)), nil
-		}
-
-		if err := mgr.WaitLoad(); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("page load failed: %v", err)), nil
-		}
-
-		title, _ := mgr.GetTitle()
-		currentURL, _ := mgr.GetURL()
-		hasCaptcha := mgr.HasCaptcha()
-
-		result := fmt.Sprintf("Navigated to: %s\nTitle: %s\nCaptcha detected: %v", currentURL, title, hasCaptcha)
-		return mcp.NewToolResultText(result), nil
-	}
+// navigateResult is the HTTP response of navigate
+type navigateResult struct {
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Captcha bool   `json:"captcha_found"`
 }
 
-// NavigateTool returns the tool definition for navigate
-func NavigateTool() mcp.Tool {
-	return mcp.NewTool(
-		"navigate",
-		mcp.WithDescription("Navigate to a URL. Returns the page title and whether a captcha was detected."),
-		mcp.WithString("url",
-			mcp.Required(),
-			mcp.Description("The URL to navigate to"),
-		),
-	)
+// NavigateTool declares the navigate tool
+func NavigateTool() Def {
+	return Def{
+		Name:        "navigate",
+		Description: "Navigate to a URL. Returns the page title and whether a captcha was detected. URLs outside the configured URL policy are refused, as are local files, browser pages and private network addresses unless allowed.",
+		Params: []Param{
+			{Name: "url", Type: TypeString, Required: true,
+				Description: "The URL to navigate to"},
+		},
+		Output:    navigateResult{},
+		Refusable: true,
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			url := args.String("url")
+			reportProgress(ctx, 0, 2, "Navigating to "+url)
+			if err := mgr.Navigate(ctx, url); err != nil {
+				return nil, fmt.Errorf("navigation failed: %w", err)
+			}
+
+			reportProgress(ctx, 1, 2, "Waiting for the page to load")
+			if err := mgr.WaitLoad(ctx); err != nil {
+				return nil, fmt.Errorf("page load failed: %w", err)
+			}
+
+			var out navigateResult
+			out.Title, _ = mgr.GetTitle()
+			out.URL, _ = mgr.GetURL()
+			out.Captcha = mgr.HasCaptcha()
+
+			text := fmt.Sprintf("Navigated to: %s\nTitle: %s\nCaptcha detected: %v", out.URL, out.Title, out.Captcha)
+			return textResult(text, out), nil
+		},
+	}
 }
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// pdfResult is the HTTP response of save_pdf
+type pdfResult struct {
+	PDF      []byte `json:"pdf,omitempty" description:"Base64 encoded PDF, when not saved"`
+	MIMEType string `json:"mimeType,omitempty"`
+	Path     string `json:"path,omitempty" description:"Where the PDF was written, when saved"`
+	Bytes    int    `json:"bytes"`
+}
+
+// SavePDFTool declares the save_pdf tool
+func SavePDFTool() Def {
+	return Def{
+		Name:        "save_pdf",
+		Description: "Print the current page to PDF, e.g. to archive a receipt or invoice. Returns the PDF, or writes it to the output directory when save or filename is set.",
+		Params: []Param{
+			{Name: "paper", Type: TypeString, Default: "letter", Enum: []string{"letter", "legal", "tabloid", "a3", "a4", "a5"},
+				Description: "Paper size"},
+			{Name: "landscape", Type: TypeBoolean, Default: false,
+				Description: "Use landscape orientation"},
+			{Name: "background", Type: TypeBoolean, Default: false,
+				Description: "Print background colors and images"},
+			{Name: "margin", Type: TypeNumber, Min: bound(0),
+				Description: "Margin on every side in inches (default: 0.4)"},
+			{Name: "page_ranges", Type: TypeString,
+				Description: "Pages to print, e.g. '1-5, 8, 11-13' (default: all)"},
+			{Name: "save", Type: TypeBoolean, Default: false,
+				Description: "Write the PDF to the output directory instead of returning it"},
+			{Name: "filename", Type: TypeString,
+				Description: "File name to save the PDF under in the output directory; implies save"},
+		},
+		Method: http.MethodGet,
+		Path:   "/pdf",
+		Output: pdfResult{},
+		Run:    savePDF,
+	}
+}
+
+func savePDF(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+	opts := browser.PDFOptions{
+		Paper:      args.String("paper"),
+		Landscape:  args.Bool("landscape"),
+		Background: args.Bool("background"),
+		PageRanges: args.String("page_ranges"),
+	}
+	if args.Has("margin") {
+		margin := args.Float("margin")
+		opts.Margin = &margin
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, &ArgError{Message: err.Error()}
+	}
+
+	data, err := mgr.PDF(opts)
+	if err != nil {
+		return nil, fmt.Errorf("save_pdf failed: %w", err)
+	}
+
+	filename := args.String("filename")
+	if filename != "" || args.Bool("save") {
+		path, err := mgr.SaveOutput(filename, ".pdf", data)
+		if err != nil {
+			return nil, fmt.Errorf("save_pdf failed: %w", err)
+		}
+		return textResult(fmt.Sprintf("Saved PDF (%d bytes) to: %s", len(data), path), pdfResult{Path: path, Bytes: len(data)}), nil
+	}
+
+	name := filepath.Base(mgr.OutputPath(filename, ".pdf"))
+	return &Result{
+		Content: []mcp.Content{
+			mcp.NewTextContent(fmt.Sprintf("Printed page to PDF (%d bytes)", len(data))),
+			mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+				URI:      "surfmate://pdf/" + name,
+				MIMEType: "application/pdf",
+				Blob:     base64.StdEncoding.EncodeToString(data),
+			}),
+		},
+		Data: pdfResult{PDF: data, MIMEType: "application/pdf", Bytes: len(data)},
+	}, nil
+}
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressKey holds the progress token of an MCP tool call in its context
+type progressKey struct{}
+
+// progressToken returns ctx with the progress token the client sent with
+// req, if any
+func progressToken(ctx context.Context, req mcp.CallToolRequest) context.Context {
+	if req.Params.Meta == nil || req.Params.Meta.ProgressToken == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, req.Params.Meta.ProgressToken)
+}
+
+// reportProgress tells the MCP client how far a long tool call has got when
+// it asked for progress. progress must increase with every report and total
+// is 0 when unknown. HTTP requests and calls without a token ignore it.
+func reportProgress(ctx context.Context, progress, total float64, message string) {
+	token := ctx.Value(progressKey{})
+	s := server.ServerFromContext(ctx)
+	if token == nil || s == nil {
+		return
+	}
+
+	params := map[string]any{
+		"progressToken": token,
+		"progress":      progress,
+		"message":       message,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	// A client that isn't reading notifications only misses the update
+	_ = s.SendNotificationToClient(ctx, "notifications/progress", params)
+}
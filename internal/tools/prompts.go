@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// PromptArg declares one argument of a prompt
+type PromptArg struct {
+	Name        string
+	Description string
+	Required    bool
+	Default     string // filled in when the argument is not given
+}
+
+// PromptDef declares a prompt: a ready-made instruction set for a common
+// workflow, written in terms of the browser tools
+type PromptDef struct {
+	Name        string
+	Description string
+	Args        []PromptArg
+
+	// Render returns the instructions for arguments with defaults filled in
+	Render func(args map[string]string) (string, error)
+}
+
+// Prompt returns the MCP prompt definition
+func (d PromptDef) Prompt() mcp.Prompt {
+	opts := []mcp.PromptOption{mcp.WithPromptDescription(d.Description)}
+	for _, a := range d.Args {
+		argOpts := []mcp.ArgumentOption{mcp.ArgumentDescription(a.Description)}
+		if a.Required {
+			argOpts = append(argOpts, mcp.RequiredArgument())
+		}
+		opts = append(opts, mcp.WithArgument(a.Name, argOpts...))
+	}
+	return mcp.NewPrompt(d.Name, opts...)
+}
+
+// Handler returns the MCP handler, which checks the arguments and renders
+// the instructions as a user message
+func (d PromptDef) Handler() server.PromptHandlerFunc {
+	return func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := map[string]string{}
+		for _, a := range d.Args {
+			v := strings.TrimSpace(req.Params.Arguments[a.Name])
+			switch {
+			case v != "":
+				args[a.Name] = v
+			case a.Required:
+				return nil, argErrorf("%s is required", a.Name)
+			default:
+				args[a.Name] = a.Default
+			}
+		}
+		for name := range req.Params.Arguments {
+			if _, ok := args[name]; !ok {
+				return nil, argErrorf("unknown argument %s", name)
+			}
+		}
+
+		text, err := d.Render(args)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewGetPromptResult(d.Description, []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+		}), nil
+	}
+}
+
+// Prompts returns every workflow prompt
+func Prompts() []PromptDef {
+	return []PromptDef{
+		ResearchPrompt(),
+		LoginPrompt(),
+		ExtractTablePrompt(),
+		FillFormPrompt(),
+	}
+}
+
+// RegisterPrompts registers all workflow prompts with the MCP server
+func RegisterPrompts(s *server.MCPServer) {
+	for _, p := range Prompts() {
+		s.AddPrompt(p.Prompt(), p.Handler())
+	}
+}
+
+// ResearchPrompt declares the research prompt
+func ResearchPrompt() PromptDef {
+	return PromptDef{
+		Name:        "research",
+		Description: "Research a topic across several web pages and summarize what they say, citing each page",
+		Args: []PromptArg{
+			{Name: "topic", Description: "What to research", Required: true},
+			{Name: "pages", Description: "How many pages to read, 1 to 10", Default: "3"},
+			{Name: "start_url", Description: "Page to search from", Default: "https://duckduckgo.com"},
+		},
+		Render: func(args map[string]string) (string, error) {
+			pages, err := strconv.Atoi(args["pages"])
+			if err != nil || pages < 1 || pages > 10 {
+				return "", argErrorf("pages must be a whole number from 1 to 10")
+			}
+			return fmt.Sprintf(`Research this topic in the browser: %s
+
+1. Call open_browser, then navigate to %s.
+2. Search for the topic with type (submit=true) on the search box, e.g. selector "role=searchbox" or "input[name=q]".
+3. Pick the %d most relevant results that come from different sites. Skip ads and sponsored results.
+4. For each one, navigate to it and read it with get_page_content. If the page is long, read only the relevant part with extract_text on its main content, e.g. "main" or "article".
+5. If navigate reports a captcha or a consent wall you can't dismiss with click, call wait_for_user with a reason saying what the user should do, then continue.
+6. Summarize what the pages agree and disagree on. Cite every claim with the URL of the page it came from. Say so when fewer than %d pages were usable.`,
+				args["topic"], args["start_url"], pages, pages), nil
+		},
+	}
+}
+
+// LoginPrompt declares the login prompt
+func LoginPrompt() PromptDef {
+	return PromptDef{
+		Name:        "login",
+		Description: "Open a site's login page and let the user sign in, filling in configured secrets when there are any",
+		Args: []PromptArg{
+			{Name: "url", Description: "Login page of the site", Required: true},
+			{Name: "username_secret", Description: "Name of the secret holding the username, if configured"},
+			{Name: "password_secret", Description: "Name of the secret holding the password, if configured"},
+		},
+		Render: func(args map[string]string) (string, error) {
+			var b strings.Builder
+			fmt.Fprintf(&b, "Sign in to %s in the browser.\n\n", args["url"])
+			fmt.Fprintf(&b, "1. Call open_browser, then navigate to %s.\n", args["url"])
+			if args["username_secret"] != "" || args["password_secret"] != "" {
+				b.WriteString("2. Fill in the login form with type. Never ask for or write out the credentials; type the secret references below exactly as written:\n")
+				if args["username_secret"] != "" {
+					fmt.Fprintf(&b, "   - username or email: {{secret:%s}}, e.g. selector \"label=Email\"\n", args["username_secret"])
+				}
+				if args["password_secret"] != "" {
+					fmt.Fprintf(&b, "   - password: {{secret:%s}}, e.g. selector \"input[type=password]\", with submit=true\n", args["password_secret"])
+				}
+				b.WriteString("   If the form asks for one field at a time, click the continue button between them.\n")
+				b.WriteString("3. If a captcha, a one-time code or any other check appears, call wait_for_user with a reason saying what the user should do.\n")
+			} else {
+				b.WriteString("2. Don't type any credentials. Tell the user the login page is open, then call wait_for_user with reason \"Log in to the site\".\n")
+				b.WriteString("3. If wait_for_user times out, ask the user whether they need more time before calling it again.\n")
+			}
+			b.WriteString("4. Confirm the login worked with get_page_content, e.g. by finding the user's name or a sign out link, and report the result without repeating any secret.")
+			return b.String(), nil
+		},
+	}
+}
+
+// ExtractTablePrompt declares the extract_table prompt
+func ExtractTablePrompt() PromptDef {
+	return PromptDef{
+		Name:        "extract_table",
+		Description: "Extract a table from a web page as CSV",
+		Args: []PromptArg{
+			{Name: "url", Description: "Page with the table", Required: true},
+			{Name: "table", Description: "Which table, e.g. its caption, a heading near it or a selector", Default: "the main data table"},
+		},
+		Render: func(args map[string]string) (string, error) {
+			return fmt.Sprintf(`Extract %s from %s as CSV.
+
+1. Call open_browser, then navigate to %s.
+2. Find the table and a CSS selector for it, e.g. from get_page_content with include_html=true. Call screenshot with annotate=true if it's unclear which table is meant.
+3. Read the header cells with extract_text, multiple=true and the table's CSS selector followed by " th", e.g. "table.results th".
+4. Read the rows the same way with " tbody tr" after the table's selector. Split each row into cells; if that is ambiguous, read the cells with " tbody td" instead.
+5. If the table is paginated or loads more rows on scroll, click the next page button or scroll and repeat until all rows are read, without duplicating rows.
+6. Reply with the CSV only: a header row, then one line per row. Quote fields containing commas, quotes or line breaks and double any quotes inside them.`,
+				args["table"], args["url"], args["url"]), nil
+		},
+	}
+}
+
+// FillFormPrompt declares the fill_form prompt
+func FillFormPrompt() PromptDef {
+	return PromptDef{
+		Name:        "fill_form",
+		Description: "Fill in a web form from the given data and stop before submitting it unless asked to",
+		Args: []PromptArg{
+			{Name: "url", Description: "Page with the form", Required: true},
+			{Name: "data", Description: "The values to enter, as JSON or one field: value per line", Required: true},
+			{Name: "submit", Description: "Whether to submit the form when it is filled in, true or false", Default: "false"},
+		},
+		Render: func(args map[string]string) (string, error) {
+			submit, err := strconv.ParseBool(args["submit"])
+			if err != nil {
+				return "", argErrorf("submit must be true or false")
+			}
+			last := "7. Don't submit the form. Tell the user it is filled in and ready for them to review and submit."
+			if submit {
+				last = "7. Submit the form with click on its submit button, then report the confirmation or any errors the page shows."
+			}
+			return fmt.Sprintf(`Fill in the form at %s with this data:
+
+%s
+
+1. Call open_browser, then navigate to %s.
+2. Call screenshot with annotate=true to see the form fields.
+3. Match each value to its field by label, not by position. Use selectors like "label=Email" or the ref= of the field.
+4. Fill text fields with type. For checkboxes, radio buttons and custom dropdowns use click, for example on the locator role=option[name="Value"]. Use run_actions to do several steps in one call.
+5. Write {{secret:name}} for values that refer to a configured secret rather than asking for them.
+6. Take another annotated screenshot and check every field. Fix anything that's wrong and list data you couldn't place.
+%s`,
+				args["url"], args["data"], args["url"], last), nil
+		},
+	}
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// getPrompt renders prompt p with args through its MCP handler
+func getPrompt(t *testing.T, p PromptDef, args map[string]string) (string, error) {
+	t.Helper()
+	var req mcp.GetPromptRequest
+	req.Params.Name = p.Name
+	req.Params.Arguments = args
+	res, err := p.Handler()(context.Background(), req)
+	if err != nil {
+		return "", err
+	}
+	if len(res.Messages) != 1 || res.Messages[0].Role != mcp.RoleUser {
+		t.Fatalf("%s returned %d messages, want one user message", p.Name, len(res.Messages))
+	}
+	text, ok := res.Messages[0].Content.(mcp.TextContent)
+	if !ok {
+		t.Fatalf("%s returned %T, want text", p.Name, res.Messages[0].Content)
+	}
+	return text.Text, nil
+}
+
+func TestPrompts(t *testing.T) {
+	tests := []struct {
+		prompt   PromptDef
+		args     map[string]string
+		wantErr  string
+		contains []string
+		tools    []string // tools the instructions must call
+	}{
+		{
+			prompt:   ResearchPrompt(),
+			args:     map[string]string{"topic": "solar panels"},
+			contains: []string{"solar panels", "https://duckduckgo.com", "Pick the 3 most relevant"},
+			tools:    []string{"open_browser", "navigate", "type", "get_page_content", "extract_text", "wait_for_user"},
+		},
+		{
+			prompt:   ResearchPrompt(),
+			args:     map[string]string{"topic": "x", "pages": "7", "start_url": "https://example.com"},
+			contains: []string{"Pick the 7 most relevant", "navigate to https://example.com"},
+		},
+		{prompt: ResearchPrompt(), args: map[string]string{"topic": "x", "pages": "11"}, wantErr: "pages must be a whole number from 1 to 10"},
+		{prompt: ResearchPrompt(), args: map[string]string{"topic": "x", "pages": "two"}, wantErr: "pages must be a whole number from 1 to 10"},
+		{prompt: ResearchPrompt(), args: map[string]string{"topic": "  "}, wantErr: "topic is required"},
+		{prompt: ResearchPrompt(), args: map[string]string{"topic": "x", "depth": "2"}, wantErr: "unknown argument depth"},
+		{
+			prompt:   LoginPrompt(),
+			args:     map[string]string{"url": "https://example.com/login"},
+			contains: []string{"Don't type any credentials"},
+			tools:    []string{"open_browser", "navigate", "wait_for_user", "get_page_content"},
+		},
+		{
+			prompt:   LoginPrompt(),
+			args:     map[string]string{"url": "https://example.com/login", "username_secret": "user", "password_secret": "pw"},
+			contains: []string{"{{secret:user}}", "{{secret:pw}}"},
+			tools:    []string{"open_browser", "navigate", "type", "wait_for_user", "get_page_content"},
+		},
+		{prompt: LoginPrompt(), args: map[string]string{}, wantErr: "url is required"},
+		{
+			prompt:   ExtractTablePrompt(),
+			args:     map[string]string{"url": "https://example.com/t"},
+			contains: []string{"Extract the main data table from https://example.com/t"},
+			tools:    []string{"open_browser", "navigate", "get_page_content", "screenshot", "extract_text", "click", "scroll"},
+		},
+		{
+			prompt:   FillFormPrompt(),
+			args:     map[string]string{"url": "https://example.com/f", "data": "Name: Ada"},
+			contains: []string{"Name: Ada", "Don't submit the form"},
+			tools:    []string{"open_browser", "navigate", "screenshot", "type", "click", "run_actions"},
+		},
+		{
+			prompt:   FillFormPrompt(),
+			args:     map[string]string{"url": "https://example.com/f", "data": "Name: Ada", "submit": "true"},
+			contains: []string{"Submit the form with click"},
+		},
+		{prompt: FillFormPrompt(), args: map[string]string{"url": "https://example.com/f", "data": "x", "submit": "maybe"}, wantErr: "submit must be true or false"},
+		{prompt: FillFormPrompt(), args: map[string]string{"url": "https://example.com/f"}, wantErr: "data is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prompt.Name, func(t *testing.T) {
+			text, err := getPrompt(t, tt.prompt, tt.args)
+			if tt.wantErr != "" {
+				var argErr *ArgError
+				if !errors.As(err, &argErr) || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want *ArgError %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(text, s) {
+					t.Errorf("instructions don't contain %q:\n%s", s, text)
+				}
+			}
+			for _, tool := range tt.tools {
+				if !strings.Contains(text, tool) {
+					t.Errorf("instructions don't mention %s:\n%s", tool, text)
+				}
+			}
+		})
+	}
+}
+
+// TestPromptsMatchTools checks that the tools and arguments the prompts name
+// exist, so the instructions don't drift from the registry
+func TestPromptsMatchTools(t *testing.T) {
+	tools := map[string][]Param{}
+	var allParams []Param
+	for _, d := range Defs() {
+		tools[d.Name] = d.Params
+		allParams = append(allParams, d.Params...)
+	}
+
+	used := map[string]bool{}
+	argRe := regexp.MustCompile(`\b([a-z_]+)=(?:true|false)\b`)
+	toolRe := regexp.MustCompile(`\b[a-z]+_[a-z_]+\b`)
+	for _, p := range Prompts() {
+		args := map[string]string{}
+		for _, a := range p.Args {
+			args[a.Name] = "x"
+			if a.Default != "" {
+				args[a.Name] = a.Default
+			}
+		}
+		text, err := getPrompt(t, p, args)
+		if err != nil {
+			t.Fatalf("%s: %v", p.Name, err)
+		}
+
+		for _, name := range toolRe.FindAllString(text, -1) {
+			if _, ok := tools[name]; ok {
+				used[name] = true
+				continue
+			}
+			if !slices.ContainsFunc(allParams, func(param Param) bool { return param.Name == name }) {
+				t.Errorf("%s names %s, which is neither a tool nor an argument", p.Name, name)
+			}
+		}
+		for _, m := range argRe.FindAllStringSubmatch(text, -1) {
+			if !slices.ContainsFunc(allParams, func(param Param) bool { return param.Name == m[1] && param.Type == TypeBoolean }) {
+				t.Errorf("%s uses %s, which no tool takes as a boolean argument", p.Name, m[0])
+			}
+		}
+	}
+
+	for _, name := range []string{"open_browser", "get_page_content", "extract_text", "wait_for_user", "run_actions"} {
+		if !used[name] {
+			t.Errorf("no prompt mentions %s; was it renamed?", name)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+)
+
+// startRecordingResult is the HTTP response of start_recording
+type startRecordingResult struct {
+	Status string `json:"status" enum:"recording"`
+}
+
+// StartRecordingTool declares the start_recording tool
+func StartRecordingTool() Def {
+	return Def{
+		Name:        "start_recording",
+		Description: "Start recording a video of the browser page, for reviewing what happened during a session. Long recordings keep only their last few minutes.",
+		Output:      startRecordingResult{},
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			if err := mgr.StartRecording(); err != nil {
+				return nil, fmt.Errorf("start_recording failed: %w", err)
+			}
+			return textResult("Recording started. Call stop_recording to save it.", startRecordingResult{Status: "recording"}), nil
+		},
+	}
+}
+
+// stopRecordingResult is the HTTP response of stop_recording
+type stopRecordingResult struct {
+	Path   string `json:"path" description:"Where the recording was written"`
+	Frames int    `json:"frames"`
+}
+
+// StopRecordingTool declares the stop_recording tool
+func StopRecordingTool() Def {
+	return Def{
+		Name:        "stop_recording",
+		Description: "Stop the recording started by start_recording and save it to the output directory.",
+		Params: []Param{
+			{Name: "format", Type: TypeString, Enum: []string{browser.RecordingGIF, browser.RecordingZip},
+				Description: "gif for an animated image, zip for the individual JPEG frames with timings (default: RECORDING_FORMAT, gif)"},
+			{Name: "filename", Type: TypeString,
+				Description: "File name in the output directory (default: timestamped)"},
+		},
+		Output: stopRecordingResult{},
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			path, frames, err := mgr.StopRecording(args.String("format"), args.String("filename"))
+			if err != nil {
+				return nil, fmt.Errorf("stop_recording failed: %w", err)
+			}
+			return textResult(fmt.Sprintf("Recording saved (%d frames) to: %s", frames, path), stopRecordingResult{Path: path, Frames: frames}), nil
+		},
+	}
+}
@@ -0,0 +1,29 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// recoverBrowser restarts a crashed or disconnected browser before a tool
+// runs, adding a notice to its result. open_browser always restarts it;
+// other tools report the lost session when AUTO_RELAUNCH is off.
+func recoverBrowser(mgr *browser.Manager) Middleware {
+	return func(name string, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			notice, err := mgr.Recover(name == "open_browser")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			res, err := next(ctx, req)
+			if notice != "" && res != nil {
+				res.Content = append([]mcp.Content{mcp.NewTextContent(notice)}, res.Content...)
+			}
+			return res, err
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+)
+
+// regionParams are the tool arguments overriding what region and language
+// the browser reports
+var regionParams = []Param{
+	{Name: "latitude", Type: TypeNumber, Min: bound(-90), Max: bound(90),
+		Description: "Geolocation latitude in degrees; requires longitude"},
+	{Name: "longitude", Type: TypeNumber, Min: bound(-180), Max: bound(180),
+		Description: "Geolocation longitude in degrees; requires latitude"},
+	{Name: "accuracy", Type: TypeNumber, Min: bound(0),
+		Description: "Geolocation accuracy in meters (default: 100)"},
+	{Name: "timezone", Type: TypeString,
+		Description: "IANA timezone ID such as Europe/Berlin, empty for the system timezone"},
+	{Name: "locale", Type: TypeString,
+		Description: "Locale such as de-DE, also sent as Accept-Language; empty for the browser's own"},
+	{Name: "user_agent", Type: TypeString,
+		Description: "User agent string, empty for the browser's own"},
+	geolocationParam,
+}
+
+// geolocationParam is the geolocation object HTTP clients sent before
+// latitude, longitude and accuracy
+var geolocationParam = Param{Name: "geolocation", Type: TypeObject, Deprecated: true,
+	Properties: map[string]any{
+		"latitude":  map[string]any{"type": TypeNumber},
+		"longitude": map[string]any{"type": TypeNumber},
+		"accuracy":  map[string]any{"type": TypeNumber},
+	},
+	Description: "Deprecated: use latitude, longitude and accuracy"}
+
+// regionUpdate reads regionParams from the arguments. It reports whether any
+// were given.
+func regionUpdate(args Args) (browser.EmulationUpdate, bool, error) {
+	var u browser.EmulationUpdate
+	given := false
+
+	if args.Has("latitude") != args.Has("longitude") {
+		return u, false, argErrorf("latitude and longitude must be given together")
+	}
+	if args.Has("latitude") {
+		u.Geolocation = &browser.Geolocation{
+			Latitude:  args.Float("latitude"),
+			Longitude: args.Float("longitude"),
+			Accuracy:  args.Float("accuracy"),
+		}
+		given = true
+	} else if args.Has("geolocation") {
+		u.Geolocation = &browser.Geolocation{}
+		if err := args.Decode("geolocation", u.Geolocation); err != nil {
+			return u, false, err
+		}
+		given = true
+	}
+	if tz := args.StringPtr("timezone"); tz != nil {
+		u.Timezone = tz
+		given = true
+	}
+	if l := args.StringPtr("locale"); l != nil {
+		u.Locale = l
+		given = true
+	}
+	if ua := args.StringPtr("user_agent"); ua != nil {
+		u.UserAgent = ua
+		given = true
+	}
+	return u, given, nil
+}
+
+// SetEmulationTool declares the set_emulation tool
+func SetEmulationTool() Def {
+	return Def{
+		Name:        "set_emulation",
+		Description: "Override the geolocation, timezone, locale and user agent the browser reports, to see a site as users in another region do. Settings not given are kept. Navigate again for the changes to reach the server.",
+		Params: append([]Param{
+			{Name: "clear_geolocation", Type: TypeBoolean, Default: false,
+				Description: "Remove the geolocation override"},
+		}, regionParams...),
+		Output: browser.Emulation{},
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			u, _, err := regionUpdate(args)
+			if err != nil {
+				return nil, err
+			}
+			u.ClearGeolocation = args.Bool("clear_geolocation")
+
+			e, err := mgr.Emulate(u)
+			if err != nil {
+				return nil, fmt.Errorf("emulation failed: %w", err)
+			}
+			return textResult("Emulation applied.\n"+e.String(), e), nil
+		},
+	}
+}
@@ -1,28 +1,149 @@
 package tools
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-// RegisterAll registers all browser tools with the MCP server
-func RegisterAll(s *server.MCPServer, mgr *browser.Manager) {
-	// Browser lifecycle
-	s.AddTool(OpenBrowserTool(), OpenBrowserHandler(mgr))
+// Def declares a tool once. MCP tool definitions, HTTP routes and the
+// OpenAPI document are all generated from it.
+type Def struct {
+	Name        string
+	Description string
+	Params      []Param
+
+	// Method and Path route the tool over HTTP, POST /Name by default. GET
+	// routes take their arguments from the query string.
+	Method string
+	Path   string
+
+	// Output is a zero value of the type Run returns as Result.Data, which
+	// documents the HTTP response
+	Output any
+
+	// Refusable tools may be refused by the URL policy or the user
+	Refusable bool
+
+	// Run performs the tool with validated arguments, giving up when ctx is
+	// done
+	Run func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error)
+}
+
+// Result is what a tool returns: content for MCP clients and data encoded
+// as the JSON response for HTTP clients
+type Result struct {
+	Content []mcp.Content
+	Data    any
+}
+
+// textResult returns a Result with a single text content
+func textResult(text string, data any) *Result {
+	return &Result{Content: []mcp.Content{mcp.NewTextContent(text)}, Data: data}
+}
+
+// Route returns the HTTP method and path of the tool
+func (d Def) Route() (string, string) {
+	method, path := d.Method, d.Path
+	if method == "" {
+		method = http.MethodPost
+	}
+	if path == "" {
+		path = "/" + d.Name
+	}
+	return method, path
+}
+
+// Tool returns the MCP tool definition
+func (d Def) Tool() mcp.Tool {
+	t := mcp.NewTool(d.Name, mcp.WithDescription(d.Description))
+	for _, p := range d.Params {
+		if p.Deprecated {
+			continue
+		}
+		t.InputSchema.Properties[p.Name] = p.Schema()
+		if p.Required {
+			t.InputSchema.Required = append(t.InputSchema.Required, p.Name)
+		}
+	}
+	return t
+}
+
+// Handler returns the MCP handler, which validates the arguments, lets Run
+// report progress when the client asked for it and reports failures as tool
+// errors
+func (d Def) Handler(mgr *browser.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := Validate(d.Params, req.Params.Arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		res, err := d.Run(progressToken(ctx, req), mgr, args)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return &mcp.CallToolResult{Content: res.Content}, nil
+	}
+}
+
+// Middleware wraps the handler of the tool called name, e.g. to trace calls
+type Middleware func(name string, next server.ToolHandlerFunc) server.ToolHandlerFunc
+
+// Defs returns every browser tool
+func Defs() []Def {
+	return []Def{
+		// Browser lifecycle
+		OpenBrowserTool(),
+		EmulateDeviceTool(),
+		SetEmulationTool(),
 
-	// Navigation
-	s.AddTool(NavigateTool(), NavigateHandler(mgr))
+		// Navigation
+		NavigateTool(),
 
-	// Interaction
-	s.AddTool(ClickTool(), ClickHandler(mgr))
-	s.AddTool(TypeTool(), TypeHandler(mgr))
-	s.AddTool(ScrollTool(), ScrollHandler(mgr))
+		// Interaction
+		ClickTool(),
+		TypeTool(),
+		ScrollTool(),
 
-	// Content
-	s.AddTool(GetPageContentTool(), GetPageContentHandler(mgr))
-	s.AddTool(ExtractTextTool(), ExtractTextHandler(mgr))
-	s.AddTool(ScreenshotTool(), ScreenshotHandler(mgr))
+		// Content
+		GetPageContentTool(),
+		ExtractTextTool(),
+		ScreenshotTool(),
+		SavePDFTool(),
+
+		// Batches
+		RunActionsTool(),
+
+		// Recording
+		StartRecordingTool(),
+		StopRecordingTool(),
+
+		// User intervention
+		WaitForUserTool(),
+	}
+}
+
+// All returns every browser tool paired with its MCP handler
+func All(mgr *browser.Manager) []server.ServerTool {
+	var all []server.ServerTool
+	for _, d := range Defs() {
+		all = append(all, server.ServerTool{Tool: d.Tool(), Handler: d.Handler(mgr)})
+	}
+	return all
+}
 
-	// User intervention
-	s.AddTool(WaitForUserTool(), WaitForUserHandler(mgr))
+// RegisterAll registers all browser tools with the MCP server. Each handler
+// recovers a crashed browser first, has secrets masked in its result and is
+// then wrapped in middleware, innermost first.
+func RegisterAll(s *server.MCPServer, mgr *browser.Manager, middleware ...Middleware) {
+	middleware = append([]Middleware{recoverBrowser(mgr), maskSecrets(mgr)}, middleware...)
+	for _, t := range All(mgr) {
+		for _, mw := range middleware {
+			t.Handler = mw(t.Tool.Name, t.Handler)
+		}
+		s.AddTool(t.Tool, t.Handler)
+	}
 }
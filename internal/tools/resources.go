@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Resource URIs of the current tab and the downloads
+const (
+	ContentURI    = "surfmate://tab/current/content.md"
+	ScreenshotURI = "surfmate://tab/current/screenshot.png"
+	DownloadsURI  = "surfmate://downloads/{name}"
+
+	downloadPrefix = "surfmate://downloads/"
+)
+
+// RegisterResources registers the current tab and the downloads as MCP
+// resources. Clients registered through hooks that subscribed to a resource
+// are sent notifications/resources/updated when the page loads or the
+// download finishes, so they can re-read it without calling a tool. The
+// transports pass resources/subscribe requests to the returned
+// Subscriptions.
+func RegisterResources(s *server.MCPServer, mgr *browser.Manager, hooks *server.Hooks) *Subscriptions {
+	s.AddResource(mcp.NewResource(ContentURI, "Current tab content",
+		mcp.WithResourceDescription("The visible content of the current page as Markdown"),
+		mcp.WithMIMEType("text/markdown"),
+	), readContent(mgr))
+
+	s.AddResource(mcp.NewResource(ScreenshotURI, "Current tab screenshot",
+		mcp.WithResourceDescription("A screenshot of the visible part of the current page"),
+		mcp.WithMIMEType("image/png"),
+	), readScreenshot(mgr))
+
+	s.AddResourceTemplate(mcp.NewResourceTemplate(DownloadsURI, "Download",
+		mcp.WithTemplateDescription("A file the browser downloaded, by the name given in the update notification"),
+	), readDownload(mgr))
+
+	subs := &Subscriptions{sessions: map[string]*subscriber{}}
+	hooks.AddOnRegisterSession(subs.add)
+	mgr.OnPageEvent(func(e browser.PageEvent) {
+		switch e.Type {
+		case browser.EventLoaded:
+			subs.updated(ContentURI)
+			subs.updated(ScreenshotURI)
+		case browser.EventDownloaded:
+			subs.updated(downloadPrefix + url.PathEscape(e.Name))
+		}
+	})
+	return subs
+}
+
+func readContent(mgr *browser.Manager) server.ResourceHandlerFunc {
+	return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if !mgr.IsLaunched() {
+			return nil, fmt.Errorf("browser is not open")
+		}
+		md, err := mgr.Markdown()
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{mcp.TextResourceContents{
+			URI:      ContentURI,
+			MIMEType: "text/markdown",
+			Text:     mgr.Secrets().Mask(md),
+		}}, nil
+	}
+}
+
+func readScreenshot(mgr *browser.Manager) server.ResourceHandlerFunc {
+	return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if !mgr.IsLaunched() {
+			return nil, fmt.Errorf("browser is not open")
+		}
+		opts := browser.ScreenshotOptions{Format: browser.FormatPNG}
+		img, err := mgr.Screenshot(opts)
+		if err != nil {
+			return nil, fmt.Errorf("screenshot failed: %w", err)
+		}
+		return []mcp.ResourceContents{mcp.BlobResourceContents{
+			URI:      ScreenshotURI,
+			MIMEType: opts.MIMEType(),
+			Blob:     base64.StdEncoding.EncodeToString(img),
+		}}, nil
+	}
+}
+
+func readDownload(mgr *browser.Manager) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		// Template variables arrive as a list of values
+		var name string
+		if v, ok := req.Params.Arguments["name"].([]string); ok && len(v) == 1 {
+			name = v[0]
+		}
+		data, err := mgr.ReadDownload(name)
+		if err != nil {
+			return nil, err
+		}
+		mimeType := mime.TypeByExtension(filepath.Ext(name))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		return []mcp.ResourceContents{mcp.BlobResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: mimeType,
+			Blob:     base64.StdEncoding.EncodeToString(data),
+		}}, nil
+	}
+}
+
+// Subscriptions tracks the resources each connected MCP session subscribed
+// to. mcp-go doesn't route resources/subscribe, so the transports call
+// Subscribe and Unsubscribe themselves.
+type Subscriptions struct {
+	mu       sync.Mutex
+	sessions map[string]*subscriber
+}
+
+type subscriber struct {
+	session server.ClientSession
+	uris    map[string]bool
+}
+
+// add keeps session until its context ends with the connection
+func (s *Subscriptions) add(ctx context.Context, session server.ClientSession) {
+	s.mu.Lock()
+	s.sessions[session.SessionID()] = &subscriber{session: session, uris: map[string]bool{}}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		delete(s.sessions, session.SessionID())
+		s.mu.Unlock()
+	}()
+}
+
+// Subscribe sends session notifications/resources/updated for uri, one of
+// the tab resources or a download, until it unsubscribes or disconnects
+func (s *Subscriptions) Subscribe(session, uri string) error {
+	if uri != ContentURI && uri != ScreenshotURI &&
+		(!strings.HasPrefix(uri, downloadPrefix) || uri == downloadPrefix) {
+		return argErrorf("unknown resource %s", uri)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := s.sessions[session]
+	if sub == nil {
+		return fmt.Errorf("unknown session %s", session)
+	}
+	sub.uris[uri] = true
+	return nil
+}
+
+// Unsubscribe stops the notifications for uri to session
+func (s *Subscriptions) Unsubscribe(session, uri string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := s.sessions[session]
+	if sub == nil {
+		return fmt.Errorf("unknown session %s", session)
+	}
+	delete(sub.uris, uri)
+	return nil
+}
+
+// updated sends notifications/resources/updated for uri to every session
+// that subscribed to it, dropping it for sessions that aren't keeping up
+func (s *Subscriptions) updated(uri string) {
+	n := mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: "notifications/resources/updated",
+			Params: mcp.NotificationParams{AdditionalFields: map[string]any{"uri": uri}},
+		},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.sessions {
+		if !sub.uris[uri] {
+			continue
+		}
+		select {
+		case sub.session.NotificationChannel() <- n:
+		default:
+		}
+	}
+}
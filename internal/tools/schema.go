@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Parameter types, as in JSON Schema
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeObject  = "object"
+	TypeArray   = "array"
+)
+
+// Param declares one tool argument. The same declaration describes it to MCP
+// clients and in the OpenAPI document, and validates it in both.
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+	Default     any      // filled in when the argument is not given
+	Enum        []string // allowed values of a string
+	Min, Max    *float64 // bounds of a number or integer
+	Properties  map[string]any
+	Items       map[string]any
+
+	// Deprecated arguments are still accepted over HTTP for one release but
+	// no longer offered to MCP clients
+	Deprecated bool
+}
+
+// bound returns v as a Param bound
+func bound(v float64) *float64 {
+	return &v
+}
+
+// Schema returns the JSON Schema of the argument
+func (p Param) Schema() map[string]any {
+	s := map[string]any{"type": p.Type}
+	if p.Description != "" {
+		s["description"] = p.Description
+	}
+	if p.Default != nil {
+		s["default"] = p.Default
+	}
+	if len(p.Enum) > 0 {
+		s["enum"] = p.Enum
+	}
+	if p.Min != nil {
+		s["minimum"] = *p.Min
+	}
+	if p.Max != nil {
+		s["maximum"] = *p.Max
+	}
+	if p.Properties != nil {
+		s["properties"] = p.Properties
+	}
+	if p.Items != nil {
+		s["items"] = p.Items
+	}
+	if p.Deprecated {
+		s["deprecated"] = true
+	}
+	return s
+}
+
+// ArgError reports invalid tool arguments
+type ArgError struct {
+	Message string
+}
+
+func (e *ArgError) Error() string {
+	return e.Message
+}
+
+func argErrorf(format string, args ...any) *ArgError {
+	return &ArgError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Args are the arguments of a tool call after validation. Every declared
+// argument with a default is present, integers are ints and numbers are
+// float64s.
+type Args map[string]any
+
+// Has reports whether the argument was given or has a default
+func (a Args) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// String returns a string argument, or "" when it is absent
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int returns an integer argument, or 0 when it is absent
+func (a Args) Int(name string) int {
+	i, _ := a[name].(int)
+	return i
+}
+
+// Float returns a number argument, or 0 when it is absent
+func (a Args) Float(name string) float64 {
+	f, _ := a[name].(float64)
+	return f
+}
+
+// Bool returns a boolean argument, or false when it is absent
+func (a Args) Bool(name string) bool {
+	b, _ := a[name].(bool)
+	return b
+}
+
+// StringPtr returns a string argument, or nil when it is absent
+func (a Args) StringPtr(name string) *string {
+	if s, ok := a[name].(string); ok {
+		return &s
+	}
+	return nil
+}
+
+// Decode decodes an object or array argument into v
+func (a Args) Decode(name string, v any) error {
+	data, err := json.Marshal(a[name])
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return argErrorf("invalid %s: %v", name, err)
+	}
+	return nil
+}
+
+// Into decodes all arguments into the struct v by their JSON names
+func (a Args) Into(v any) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return argErrorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+// Validate checks raw arguments against params and returns them with
+// defaults filled in for missing arguments, or an *ArgError naming the
+// first problem. Unknown arguments are ignored.
+func Validate(params []Param, raw map[string]any) (Args, error) {
+	args := Args{}
+	for _, p := range params {
+		v, ok := raw[p.Name]
+		if !ok || v == nil {
+			switch {
+			case p.Required:
+				return nil, argErrorf("%s is required", p.Name)
+			case p.Default != nil:
+				args[p.Name] = p.Default
+			}
+			continue
+		}
+
+		v, err := p.check(v)
+		if err != nil {
+			return nil, err
+		}
+		args[p.Name] = v
+	}
+	return args, nil
+}
+
+// check validates one given argument, converting integers to int
+func (p Param) check(v any) (any, error) {
+	switch p.Type {
+	case TypeString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, argErrorf("%s must be a string", p.Name)
+		}
+		if len(p.Enum) > 0 && !slices.Contains(p.Enum, s) {
+			return nil, argErrorf("%s must be one of %s", p.Name, strings.Join(p.Enum, ", "))
+		}
+		if p.Required && s == "" {
+			return nil, argErrorf("%s is required", p.Name)
+		}
+		return s, nil
+
+	case TypeInteger, TypeNumber:
+		f, ok := v.(float64)
+		if !ok {
+			if i, isInt := v.(int); isInt {
+				f, ok = float64(i), true
+			}
+		}
+		if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, argErrorf("%s must be a number", p.Name)
+		}
+		if p.Min != nil && f < *p.Min {
+			return nil, argErrorf("%s must be at least %g", p.Name, *p.Min)
+		}
+		if p.Max != nil && f > *p.Max {
+			return nil, argErrorf("%s must be at most %g", p.Name, *p.Max)
+		}
+		if p.Type == TypeNumber {
+			return f, nil
+		}
+		if f != math.Trunc(f) {
+			return nil, argErrorf("%s must be a whole number", p.Name)
+		}
+		return int(f), nil
+
+	case TypeBoolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, argErrorf("%s must be true or false", p.Name)
+		}
+		return b, nil
+
+	case TypeObject:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, argErrorf("%s must be an object", p.Name)
+		}
+		return m, nil
+
+	case TypeArray:
+		a, ok := v.([]any)
+		if !ok {
+			return nil, argErrorf("%s must be an array", p.Name)
+		}
+		if p.Required && len(a) == 0 {
+			return nil, argErrorf("%s is required", p.Name)
+		}
+		return a, nil
+	}
+	return nil, fmt.Errorf("%s has unknown type %s", p.Name, p.Type)
+}
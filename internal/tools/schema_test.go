@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	params := []Param{
+		{Name: "selector", Type: TypeString, Required: true},
+		{Name: "text", Type: TypeString, Default: ""},
+		{Name: "format", Type: TypeString, Default: "png", Enum: []string{"png", "jpeg"}},
+		{Name: "scheme", Type: TypeString, Enum: []string{"", "light", "dark"}},
+		{Name: "quality", Type: TypeInteger, Default: 80, Min: bound(1), Max: bound(100)},
+		{Name: "timeout", Type: TypeInteger, Default: 300, Min: bound(0)},
+		{Name: "margin", Type: TypeNumber, Min: bound(0)},
+		{Name: "full_page", Type: TypeBoolean, Default: false},
+		{Name: "clip", Type: TypeObject},
+		{Name: "actions", Type: TypeArray},
+	}
+	defaults := Args{"text": "", "format": "png", "quality": 80, "timeout": 300, "full_page": false}
+
+	// with returns the defaults with the given arguments added
+	with := func(extra Args) Args {
+		args := Args{"selector": "#a"}
+		for k, v := range defaults {
+			args[k] = v
+		}
+		for k, v := range extra {
+			args[k] = v
+		}
+		return args
+	}
+
+	tests := []struct {
+		name    string
+		raw     map[string]any
+		want    Args
+		wantErr string
+	}{
+		// Required
+		{name: "required given", raw: map[string]any{"selector": "#a"}, want: with(nil)},
+		{name: "required missing", raw: map[string]any{}, wantErr: "selector is required"},
+		{name: "required null", raw: map[string]any{"selector": nil}, wantErr: "selector is required"},
+		{name: "required empty", raw: map[string]any{"selector": ""}, wantErr: "selector is required"},
+		{name: "required wrong type", raw: map[string]any{"selector": 1.0}, wantErr: "selector must be a string"},
+
+		// Defaults
+		{name: "null takes default", raw: map[string]any{"selector": "#a", "timeout": nil}, want: with(nil)},
+		{name: "zero is kept", raw: map[string]any{"selector": "#a", "timeout": 0.0}, want: with(Args{"timeout": 0})},
+		{name: "zero below min", raw: map[string]any{"selector": "#a", "quality": 0.0}, wantErr: "quality must be at least 1"},
+		{name: "empty string checked against enum", raw: map[string]any{"selector": "#a", "format": ""}, wantErr: "format must be one of png, jpeg"},
+		{name: "empty text is kept", raw: map[string]any{"selector": "#a", "text": ""}, want: with(nil)},
+		{name: "false is kept", raw: map[string]any{"selector": "#a", "full_page": false}, want: with(nil)},
+		{name: "value overrides default", raw: map[string]any{"selector": "#a", "text": "hi", "full_page": true}, want: with(Args{"text": "hi", "full_page": true})},
+
+		// Min and Max
+		{name: "integer in range", raw: map[string]any{"selector": "#a", "quality": 50.0}, want: with(Args{"quality": 50})},
+		{name: "integer from int", raw: map[string]any{"selector": "#a", "quality": 50}, want: with(Args{"quality": 50})},
+		{name: "integer at min", raw: map[string]any{"selector": "#a", "quality": 1.0}, want: with(Args{"quality": 1})},
+		{name: "integer at max", raw: map[string]any{"selector": "#a", "quality": 100.0}, want: with(Args{"quality": 100})},
+		{name: "integer below min", raw: map[string]any{"selector": "#a", "quality": -5.0}, wantErr: "quality must be at least 1"},
+		{name: "integer above max", raw: map[string]any{"selector": "#a", "quality": 101.0}, wantErr: "quality must be at most 100"},
+		{name: "integer fraction", raw: map[string]any{"selector": "#a", "quality": 50.5}, wantErr: "quality must be a whole number"},
+		{name: "integer as string", raw: map[string]any{"selector": "#a", "quality": "50"}, wantErr: "quality must be a number"},
+		{name: "number fraction", raw: map[string]any{"selector": "#a", "margin": 0.5}, want: with(Args{"margin": 0.5})},
+		{name: "number below min", raw: map[string]any{"selector": "#a", "margin": -0.1}, wantErr: "margin must be at least 0"},
+
+		// Enum
+		{name: "enum value", raw: map[string]any{"selector": "#a", "format": "jpeg"}, want: with(Args{"format": "jpeg"})},
+		{name: "enum unknown value", raw: map[string]any{"selector": "#a", "format": "gif"}, wantErr: "format must be one of png, jpeg"},
+		{name: "enum case sensitive", raw: map[string]any{"selector": "#a", "format": "PNG"}, wantErr: "format must be one of png, jpeg"},
+		{name: "enum with empty value", raw: map[string]any{"selector": "#a", "scheme": ""}, want: with(Args{"scheme": ""})},
+
+		// Other types
+		{name: "boolean as string", raw: map[string]any{"selector": "#a", "full_page": "true"}, wantErr: "full_page must be true or false"},
+		{name: "object", raw: map[string]any{"selector": "#a", "clip": map[string]any{"x": 1.0}}, want: with(Args{"clip": map[string]any{"x": 1.0}})},
+		{name: "object as array", raw: map[string]any{"selector": "#a", "clip": []any{}}, wantErr: "clip must be an object"},
+		{name: "array", raw: map[string]any{"selector": "#a", "actions": []any{"a"}}, want: with(Args{"actions": []any{"a"}})},
+
+		// Unknown arguments
+		{name: "unknown ignored", raw: map[string]any{"selector": "#a", "geolocation": map[string]any{}}, want: with(nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Validate(params, tt.raw)
+			if tt.wantErr != "" {
+				var argErr *ArgError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("Validate() error = %v, want *ArgError %q", err, tt.wantErr)
+				}
+				if err.Error() != tt.wantErr {
+					t.Errorf("Validate() error = %q, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolHidesDeprecated(t *testing.T) {
+	tool := EmulateDeviceTool().Tool()
+	for _, name := range []string{"geolocation", "timezone", "locale"} {
+		if _, ok := tool.InputSchema.Properties[name]; ok {
+			t.Errorf("emulate_device offers deprecated argument %s to MCP clients", name)
+		}
+	}
+	if _, ok := tool.InputSchema.Properties["device"]; !ok {
+		t.Error("emulate_device lost its device argument")
+	}
+}
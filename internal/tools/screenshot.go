@@ -4,57 +4,88 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 
 	"github.com/afalcongonzalez/surfmate.io/internal/browser"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// ScreenshotHandler handles the screenshot tool
-func ScreenshotHandler(mgr *browser.Manager) func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		fullPage := false
-		if f, ok := req.Params.Arguments["full_page"].(bool); ok {
-			fullPage = f
-		}
-
-		selector := ""
-		if s, ok := req.Params.Arguments["selector"].(string); ok {
-			selector = s
-		}
+// screenshotResult is the HTTP response of screenshot
+type screenshotResult struct {
+	Image    []byte         `json:"image" description:"Base64 encoded image"`
+	MIMEType string         `json:"mimeType"`
+	Legend   []browser.Mark `json:"legend,omitempty" description:"Present when annotate is set"`
+}
 
-		quality := 80
-		if q, ok := req.Params.Arguments["quality"].(float64); ok {
-			quality = int(q)
-		}
+// ScreenshotTool declares the screenshot tool
+func ScreenshotTool() Def {
+	return Def{
+		Name:        "screenshot",
+		Description: "Capture a screenshot of the current page as a base64 encoded image, plus a legend of numbered elements when annotate is set. Use jpeg or webp with max_width to keep large pages small.",
+		Params: []Param{
+			{Name: "full_page", Type: TypeBoolean, Default: false,
+				Description: "Capture the full scrollable page instead of the viewport only"},
+			{Name: "selector", Type: TypeString,
+				Description: "If provided, capture only this element. " + browser.LocatorHelp},
+			{Name: "clip", Type: TypeObject,
+				Description: "If provided, capture only this region of the page, in CSS pixels relative to the document",
+				Properties: map[string]any{
+					"x":      map[string]any{"type": TypeNumber},
+					"y":      map[string]any{"type": TypeNumber},
+					"width":  map[string]any{"type": TypeNumber},
+					"height": map[string]any{"type": TypeNumber},
+				}},
+			{Name: "format", Type: TypeString, Default: browser.FormatPNG, Enum: []string{browser.FormatPNG, browser.FormatJPEG, browser.FormatWebP},
+				Description: "Image format"},
+			{Name: "quality", Type: TypeInteger, Default: browser.DefaultScreenshotQuality, Min: bound(1), Max: bound(100),
+				Description: "Image quality for jpeg and webp"},
+			{Name: "max_width", Type: TypeInteger, Min: bound(0),
+				Description: "Downscale the image to at most this many pixels wide"},
+			{Name: "max_height", Type: TypeInteger, Min: bound(0),
+				Description: "Downscale the image to at most this many pixels high"},
+			{Name: "annotate", Type: TypeBoolean, Default: false,
+				Description: "Draw a numbered box over every visible interactive element and return a legend. Each number can be clicked or typed into with the selector ref=N (ignores selector)"},
+		},
+		Method: http.MethodGet,
+		Output: screenshotResult{},
+		Run:    screenshot,
+	}
+}
 
-		data, err := mgr.Screenshot(fullPage, selector, quality)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("screenshot failed: %v", err)), nil
+func screenshot(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+	opts := browser.ScreenshotOptions{
+		FullPage:  args.Bool("full_page"),
+		Selector:  args.String("selector"),
+		Format:    args.String("format"),
+		Quality:   args.Int("quality"),
+		MaxWidth:  args.Int("max_width"),
+		MaxHeight: args.Int("max_height"),
+	}
+	if args.Has("clip") {
+		opts.Clip = &browser.Rect{}
+		if err := args.Decode("clip", opts.Clip); err != nil {
+			return nil, err
 		}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, &ArgError{Message: err.Error()}
+	}
 
-		encoded := base64.StdEncoding.EncodeToString(data)
-
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				mcp.NewImageContent(encoded, "image/png"),
-			},
-		}, nil
+	var out screenshotResult
+	var err error
+	if args.Bool("annotate") {
+		out.Image, out.Legend, err = mgr.AnnotatedScreenshot(opts)
+	} else {
+		out.Image, err = mgr.Screenshot(opts)
 	}
-}
+	if err != nil {
+		return nil, fmt.Errorf("screenshot failed: %w", err)
+	}
+	out.MIMEType = opts.MIMEType()
 
-// ScreenshotTool returns the tool definition for screenshot
-func ScreenshotTool() mcp.Tool {
-	return mcp.NewTool(
-		"screenshot",
-		mcp.WithDescription("Capture a screenshot of the current page. Returns a base64 encoded PNG image."),
-		mcp.WithBoolean("full_page",
-			mcp.Description("Capture the full scrollable page (default: false, captures viewport only)"),
-		),
-		mcp.WithString("selector",
-			mcp.Description("If provided, capture only this element"),
-		),
-		mcp.WithNumber("quality",
-			mcp.Description("Image quality 1-100 (default: 80)"),
-		),
-	)
+	content := []mcp.Content{mcp.NewImageContent(base64.StdEncoding.EncodeToString(out.Image), out.MIMEType)}
+	if args.Bool("annotate") {
+		content = append(content, mcp.NewTextContent(browser.FormatLegend(out.Legend)))
+	}
+	return &Result{Content: content, Data: out}, nil
 }
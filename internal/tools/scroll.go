@@ -5,51 +5,45 @@ import (
 	"fmt"
 
 	"github.com/afalcongonzalez/surfmate.io/internal/browser"
-	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// ScrollHandler handles the scroll tool
-func ScrollHandler(mgr *browser.Manager) func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		direction := "down"
-		if d, ok := req.Params.Arguments["direction"].(string); ok && d != "" {
-			direction = d
-		}
-
-		amount := 300
-		if a, ok := req.Params.Arguments["amount"].(float64); ok {
-			amount = int(a)
-		}
-
-		selector := ""
-		if s, ok := req.Params.Arguments["selector"].(string); ok {
-			selector = s
-		}
-
-		if err := mgr.Scroll(direction, amount, selector); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("scroll failed: %v", err)), nil
-		}
-
-		if selector != "" {
-			return mcp.NewToolResultText(fmt.Sprintf("Scrolled element into view: %s", selector)), nil
-		}
-		return mcp.NewToolResultText(fmt.Sprintf("Scrolled %s by %d pixels", direction, amount)), nil
-	}
+// scrollResult is the HTTP response of scroll
+type scrollResult struct {
+	Status    string `json:"status" enum:"scrolled"`
+	Direction string `json:"direction"`
+	Amount    int    `json:"amount"`
+	Selector  string `json:"selector,omitempty"`
 }
 
-// ScrollTool returns the tool definition for scroll
-func ScrollTool() mcp.Tool {
-	return mcp.NewTool(
-		"scroll",
-		mcp.WithDescription("Scroll the page or scroll an element into view."),
-		mcp.WithString("direction",
-			mcp.Description("Direction to scroll: up, down, left, right (default: down)"),
-		),
-		mcp.WithNumber("amount",
-			mcp.Description("Amount in pixels to scroll (default: 300)"),
-		),
-		mcp.WithString("selector",
-			mcp.Description("If provided, scroll this element into view instead of scrolling the page"),
-		),
-	)
+// ScrollTool declares the scroll tool
+func ScrollTool() Def {
+	return Def{
+		Name:        "scroll",
+		Description: "Scroll the page or scroll an element into view.",
+		Params: []Param{
+			{Name: "direction", Type: TypeString, Default: "down", Enum: []string{"up", "down", "left", "right"},
+				Description: "Direction to scroll"},
+			{Name: "amount", Type: TypeInteger, Default: 300, Min: bound(0),
+				Description: "Amount in pixels to scroll"},
+			{Name: "selector", Type: TypeString,
+				Description: "If provided, scroll this element into view instead of scrolling the page. " + browser.LocatorHelp},
+		},
+		Output: scrollResult{},
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			out := scrollResult{
+				Status:    "scrolled",
+				Direction: args.String("direction"),
+				Amount:    args.Int("amount"),
+				Selector:  args.String("selector"),
+			}
+			if err := mgr.Scroll(out.Direction, out.Amount, out.Selector); err != nil {
+				return nil, fmt.Errorf("scroll failed: %w", err)
+			}
+
+			if out.Selector != "" {
+				return textResult(fmt.Sprintf("Scrolled element into view: %s", out.Selector), out), nil
+			}
+			return textResult(fmt.Sprintf("Scrolled %s by %d pixels", out.Direction, out.Amount), out), nil
+		},
+	}
 }
@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"context"
+	"errors"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maskSecrets replaces secret values in tool results with their
+// {{secret:name}} references, so page text, URLs and errors never show them
+func maskSecrets(mgr *browser.Manager) Middleware {
+	store := mgr.Secrets()
+	return func(name string, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		if store.Empty() {
+			return next
+		}
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			res, err := next(ctx, req)
+			if err != nil {
+				err = errors.New(store.Mask(err.Error()))
+			}
+			if res != nil {
+				for i, c := range res.Content {
+					if t, ok := c.(mcp.TextContent); ok {
+						t.Text = store.Mask(t.Text)
+						res.Content[i] = t
+					}
+				}
+			}
+			return res, err
+		}
+	}
+}
@@ -5,54 +5,47 @@ import (
 	"fmt"
 
 	"github.com/afalcongonzalez/surfmate.io/internal/browser"
-	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// TypeHandler handles the type tool
-func TypeHandler(mgr *browser.Manager) func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		selector, ok := req.Params.Arguments["selector"].(string)
-		if !ok || selector == "" {
-			return mcp.NewToolResultError("selector parameter is required"), nil
-		}
-
-		text, ok := req.Params.Arguments["text"].(string)
-		if !ok {
-			return mcp.NewToolResultError("text parameter is required"), nil
-		}
-
-		submit := false
-		if s, ok := req.Params.Arguments["submit"].(bool); ok {
-			submit = s
-		}
-
-		if err := mgr.Type(selector, text, submit); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("type failed: %v", err)), nil
-		}
-
-		result := fmt.Sprintf("Typed into element: %s", selector)
-		if submit {
-			result += " (submitted)"
-		}
-		return mcp.NewToolResultText(result), nil
-	}
+// typeResult is the HTTP response of type
+type typeResult struct {
+	Status    string               `json:"status" enum:"typed"`
+	Selector  string               `json:"selector"`
+	Submitted bool                 `json:"submitted"`
+	Changes   *browser.StateChange `json:"changes,omitempty" description:"Present when report_changes is set"`
 }
 
-// TypeTool returns the tool definition for type
-func TypeTool() mcp.Tool {
-	return mcp.NewTool(
-		"type",
-		mcp.WithDescription("Type text into an input element. Optionally press Enter to submit."),
-		mcp.WithString("selector",
-			mcp.Required(),
-			mcp.Description("CSS selector or XPath to the input element"),
-		),
-		mcp.WithString("text",
-			mcp.Required(),
-			mcp.Description("Text to type into the element"),
-		),
-		mcp.WithBoolean("submit",
-			mcp.Description("Press Enter after typing to submit (default: false)"),
-		),
-	)
+// TypeTool declares the type tool
+func TypeTool() Def {
+	return Def{
+		Name:        "type",
+		Description: "Type text into an input element. Optionally press Enter to submit.",
+		Params: append([]Param{
+			{Name: "selector", Type: TypeString, Required: true,
+				Description: "Input element to type into. " + browser.LocatorHelp},
+			{Name: "text", Type: TypeString, Default: "",
+				Description: "Text to type into the element, replacing its value; empty clears it. Write {{secret:name}} to type a configured secret, such as a password, without seeing it; secret values are masked in all results"},
+			{Name: "submit", Type: TypeBoolean, Default: false,
+				Description: "Press Enter after typing to submit"},
+		}, changeParams...),
+		Output:    typeResult{},
+		Refusable: true,
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			selector, submit := args.String("selector"), args.Bool("submit")
+			a := browser.Action{Action: browser.ActionType, Selector: selector, Text: args.String("text"), Submit: submit}
+			change, err := runObserved(mgr, args, a)
+			if err != nil {
+				return nil, actionError("type", err)
+			}
+
+			text := fmt.Sprintf("Typed into element: %s", selector)
+			if submit {
+				text += " (submitted)"
+			}
+			if change != nil {
+				text += "\n" + change.String()
+			}
+			return textResult(text, typeResult{Status: "typed", Selector: selector, Submitted: submit, Changes: change}), nil
+		},
+	}
 }
@@ -3,50 +3,58 @@ package tools
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/afalcongonzalez/surfmate.io/internal/browser"
-	"github.com/mark3labs/mcp-go/mcp"
 )
 
-// WaitForUserHandler handles the wait_for_user tool
-func WaitForUserHandler(mgr *browser.Manager) func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		reason := "User intervention required"
-		if r, ok := req.Params.Arguments["reason"].(string); ok && r != "" {
-			reason = r
-		}
-
-		timeout := 5 * time.Minute
-		if t, ok := req.Params.Arguments["timeout"].(float64); ok && t > 0 {
-			timeout = time.Duration(t) * time.Second
-		}
-
-		fmt.Printf("Waiting for user: %s (timeout: %v)\n", reason, timeout)
-
-		if err := mgr.WaitForUser(timeout); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("wait failed: %v", err)), nil
-		}
-
-		hasCaptcha := mgr.HasCaptcha()
-		if hasCaptcha {
-			return mcp.NewToolResultText("Wait completed but captcha still detected. User may need more time."), nil
-		}
-
-		return mcp.NewToolResultText("User intervention completed successfully."), nil
-	}
+// waitResult is the HTTP response of wait_for_user
+type waitResult struct {
+	Status  string `json:"status" enum:"completed"`
+	Captcha bool   `json:"captcha_present" description:"Whether a captcha is still shown, when the user may need more time"`
 }
 
-// WaitForUserTool returns the tool definition for wait_for_user
-func WaitForUserTool() mcp.Tool {
-	return mcp.NewTool(
-		"wait_for_user",
-		mcp.WithDescription("Pause execution and wait for user to complete an action (like solving a captcha or logging in). Polls until captcha elements disappear or timeout."),
-		mcp.WithString("reason",
-			mcp.Description("Reason for waiting (e.g., 'Solve captcha', 'Complete login')"),
-		),
-		mcp.WithNumber("timeout",
-			mcp.Description("Maximum time to wait in seconds (default: 300)"),
-		),
-	)
+// defaultWaitTimeout is how many seconds wait_for_user waits when no
+// timeout, or a timeout of 0, is given
+const defaultWaitTimeout = 300
+
+// WaitForUserTool declares the wait_for_user tool
+func WaitForUserTool() Def {
+	return Def{
+		Name:        "wait_for_user",
+		Description: "Pause execution and wait for user to complete an action (like solving a captcha or logging in). Polls until captcha elements disappear or timeout.",
+		Params: []Param{
+			{Name: "reason", Type: TypeString, Default: "User intervention required",
+				Description: "Reason for waiting (e.g., 'Solve captcha', 'Complete login')"},
+			{Name: "timeout", Type: TypeInteger, Default: defaultWaitTimeout, Min: bound(0),
+				Description: "Maximum time to wait in seconds, 0 for the default"},
+		},
+		Path:   "/wait",
+		Output: waitResult{},
+		Run: func(ctx context.Context, mgr *browser.Manager, args Args) (*Result, error) {
+			seconds := args.Int("timeout")
+			if seconds == 0 {
+				seconds = defaultWaitTimeout
+			}
+			timeout := time.Duration(seconds) * time.Second
+
+			// stdout carries the MCP protocol over stdio
+			fmt.Fprintf(os.Stderr, "Waiting for user: %s (timeout: %v)\n", args.String("reason"), timeout)
+
+			report := func(elapsed time.Duration) {
+				reportProgress(ctx, elapsed.Seconds(), timeout.Seconds(),
+					fmt.Sprintf("Still waiting, captcha visible, %ds elapsed", int(elapsed.Seconds())))
+			}
+			if err := mgr.WaitForUser(ctx, timeout, report); err != nil {
+				return nil, fmt.Errorf("wait failed: %w", err)
+			}
+
+			out := waitResult{Status: "completed", Captcha: mgr.HasCaptcha()}
+			if out.Captcha {
+				return textResult("Wait completed but captcha still detected. User may need more time.", out), nil
+			}
+			return textResult("User intervention completed successfully.", out), nil
+		},
+	}
 }
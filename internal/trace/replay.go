@@ -0,0 +1,106 @@
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxTraceLine bounds a single trace entry, which may carry long typed text
+const maxTraceLine = 10 * 1024 * 1024
+
+// Read parses a JSONL trace
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxTraceLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid trace entry on line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Replay re-executes entries with the given tools, writing one line per step
+// to out. A step diverges when it fails where the trace succeeded or the
+// other way round, or when it ends on a different page. Pages are compared
+// by scheme, host and path, since query strings often carry session tokens.
+// Replay stops at the first divergence unless keepGoing is set, and returns
+// the number of divergent steps.
+func Replay(entries []Entry, mgr *browser.Manager, toolset []server.ServerTool, out io.Writer, keepGoing bool) (int, error) {
+	handlers := make(map[string]server.ToolHandlerFunc, len(toolset))
+	for _, t := range toolset {
+		handlers[t.Tool.Name] = t.Handler
+	}
+
+	diverged := 0
+	for _, e := range entries {
+		handler, ok := handlers[e.Tool]
+		if !ok {
+			return diverged, fmt.Errorf("step %d: unknown tool %q", e.Seq, e.Tool)
+		}
+
+		req := mcp.CallToolRequest{}
+		req.Params.Name = e.Tool
+		req.Params.Arguments = e.Arguments
+
+		start := time.Now()
+		res, err := handler(context.Background(), req)
+		took := time.Since(start).Round(time.Millisecond)
+		got := mgr.Secrets().Mask(ErrorText(res, err))
+		gotURL := mgr.Secrets().Mask(currentURL(mgr))
+
+		var problem string
+		switch {
+		case e.Error == "" && got != "":
+			problem = fmt.Sprintf("expected success, got error: %s", got)
+		case e.Error != "" && got == "":
+			problem = fmt.Sprintf("expected error %q, got success", e.Error)
+		case !samePage(e.URL, gotURL):
+			problem = fmt.Sprintf("expected URL %s, got %s", e.URL, gotURL)
+		}
+
+		if problem == "" {
+			fmt.Fprintf(out, "[%d] %s ok (%s) %s\n", e.Seq, e.Tool, took, gotURL)
+			continue
+		}
+
+		diverged++
+		fmt.Fprintf(out, "[%d] %s DIVERGED (%s): %s\n", e.Seq, e.Tool, took, problem)
+		if !keepGoing {
+			break
+		}
+	}
+	return diverged, nil
+}
+
+// samePage reports whether two URLs point at the same page, ignoring the
+// query string and fragment
+func samePage(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return ua.Scheme == ub.Scheme && ua.Host == ub.Host && ua.Path == ub.Path
+}
@@ -0,0 +1,177 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// snapshotMaxWidth keeps before/after screenshots small
+const snapshotMaxWidth = 800
+
+// Entry is one recorded tool call
+type Entry struct {
+	Seq        int                    `json:"seq"`
+	Time       time.Time              `json:"time"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+	DurationMS int64                  `json:"duration_ms"`
+	URL        string                 `json:"url,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Before     string                 `json:"before,omitempty"`
+	After      string                 `json:"after,omitempty"`
+}
+
+// Recorder appends an Entry to a JSONL trace file for every tool call
+type Recorder struct {
+	mgr         *browser.Manager
+	file        *os.File
+	name        string
+	screenshots bool
+	mu          sync.Mutex
+	seq         int
+}
+
+// NewRecorder creates the trace file at path, failing rather than replacing
+// an existing file. With screenshots set, a JPEG
+// of the page is saved to the output directory before and after each call.
+func NewRecorder(mgr *browser.Manager, path string, screenshots bool) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create trace directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace: %w", err)
+	}
+
+	return &Recorder{
+		mgr:         mgr,
+		file:        f,
+		name:        strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		screenshots: screenshots,
+	}, nil
+}
+
+// Path returns the trace file path
+func (r *Recorder) Path() string {
+	return r.file.Name()
+}
+
+// Close closes the trace file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// Middleware records every call of the tool called name. It matches
+// tools.Middleware.
+func (r *Recorder) Middleware(name string, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var res *mcp.CallToolResult
+		var err error
+		r.Record(name, req.Params.Arguments, func() string {
+			res, err = next(ctx, req)
+			return ErrorText(res, err)
+		})
+		return res, err
+	}
+}
+
+// Record records one call of the tool called name with args. call runs the
+// tool and returns the error it reported, or "" if it succeeded.
+func (r *Recorder) Record(name string, args map[string]interface{}, call func() string) {
+	r.mu.Lock()
+	r.seq++
+	entry := Entry{Seq: r.seq, Time: time.Now(), Tool: name, Arguments: args}
+	r.mu.Unlock()
+
+	if r.screenshots {
+		entry.Before = r.snapshot(entry.Seq, "before")
+	}
+
+	entry.Error = call()
+
+	entry.DurationMS = time.Since(entry.Time).Milliseconds()
+	entry.URL = currentURL(r.mgr)
+	if r.screenshots {
+		entry.After = r.snapshot(entry.Seq, "after")
+	}
+
+	if err := r.write(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Trace error: %v\n", err)
+	}
+}
+
+func (r *Recorder) write(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = r.mgr.Secrets().MaskJSON(data)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.file.Write(append(data, '\n'))
+	return err
+}
+
+// snapshot saves a screenshot for a trace step, returning its file name or
+// "" when there is no page to capture
+func (r *Recorder) snapshot(seq int, stage string) string {
+	if !r.mgr.IsLaunched() {
+		return ""
+	}
+
+	data, err := r.mgr.Screenshot(browser.ScreenshotOptions{Format: browser.FormatJPEG, MaxWidth: snapshotMaxWidth})
+	if err != nil {
+		return ""
+	}
+	path, err := r.mgr.SaveOutput(fmt.Sprintf("%s-%04d-%s", r.name, seq, stage), ".jpg", data)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(path)
+}
+
+// ErrorText returns the error reported by a tool call, or "" if it succeeded
+func ErrorText(res *mcp.CallToolResult, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if res == nil || !res.IsError {
+		return ""
+	}
+
+	var texts []string
+	for _, c := range res.Content {
+		if t, ok := c.(mcp.TextContent); ok {
+			texts = append(texts, t.Text)
+		}
+	}
+	if len(texts) == 0 {
+		return "tool returned an error"
+	}
+	return strings.Join(texts, "\n")
+}
+
+// currentURL returns the page URL, or "" when the browser is not open
+func currentURL(mgr *browser.Manager) string {
+	if !mgr.IsLaunched() {
+		return ""
+	}
+	url, err := mgr.GetURL()
+	if err != nil {
+		return ""
+	}
+	return url
+}
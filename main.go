@@ -6,58 +6,116 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/afalcongonzalez/surfmate.io/internal/browser"
 	"github.com/afalcongonzalez/surfmate.io/internal/config"
 	httpserver "github.com/afalcongonzalez/surfmate.io/internal/server"
 	"github.com/afalcongonzalez/surfmate.io/internal/tools"
+	"github.com/afalcongonzalez/surfmate.io/internal/trace"
 	"github.com/mark3labs/mcp-go/server"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			os.Exit(runReplay(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		}
+	}
+
 	// Parse flags
 	httpMode := flag.Bool("http", false, "Run as HTTP server instead of MCP")
-	port := flag.Int("port", 8080, "HTTP server port (only used with -http)")
+	mcpHTTPMode := flag.Bool("mcp-http", false, "Serve MCP over HTTP with SSE instead of stdio, for remote clients")
+	configPath := config.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
-	cfg := config.Load()
+	cfg, err := loadConfig(*configPath, flag.CommandLine)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		os.Exit(1)
+	}
+	if *httpMode && *mcpHTTPMode {
+		fmt.Fprintln(os.Stderr, "Use either -http or -mcp-http")
+		os.Exit(2)
+	}
 
 	// Create browser manager
 	mgr := browser.GetManager(cfg)
 
-	// Handle shutdown
+	// Trace tool calls if enabled
+	var rec *trace.Recorder
+	if cfg.Trace {
+		name := "trace-" + time.Now().Format("20060102-150405")
+		rec, err = trace.NewRecorder(mgr, mgr.OutputPath(name, ".jsonl"), cfg.TraceShots)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Trace error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Tracing tool calls to %s\n", rec.Path())
+	}
+
+	// Handle shutdown. os.Exit skips deferred calls, so every exit closes
+	// the trace and the browser here.
+	exit := func(code int) {
+		if rec != nil {
+			rec.Close()
+		}
+		mgr.Close()
+		os.Exit(code)
+	}
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigChan
-		mgr.Close()
-		os.Exit(0)
+		exit(0)
 	}()
 
 	if *httpMode {
 		// Run HTTP server for ChatGPT Actions
-		srv := httpserver.NewHTTPServer(mgr, *port)
+		srv := httpserver.NewHTTPServer(mgr, cfg)
+		srv.SetRecorder(rec)
 		if err := srv.Start(); err != nil {
 			fmt.Fprintf(os.Stderr, "HTTP server error: %v\n", err)
-			mgr.Close()
-			os.Exit(1)
+			exit(1)
 		}
 	} else {
-		// Run MCP server (default)
+		// Run MCP server (default), over stdio or HTTP
+		hooks := &server.Hooks{}
 		s := server.NewMCPServer(
 			"surfmate.io",
 			"1.0.0",
 			server.WithToolCapabilities(true),
+			server.WithResourceCapabilities(true, false),
+			server.WithPromptCapabilities(false),
+			server.WithHooks(hooks),
 		)
 
-		// Register all tools
-		tools.RegisterAll(s, mgr)
+		// Register all tools, tracing calls if enabled
+		var middleware []tools.Middleware
+		if rec != nil {
+			middleware = append(middleware, rec.Middleware)
+		}
+		tools.RegisterAll(s, mgr, middleware...)
+		subs := tools.RegisterResources(s, mgr, hooks)
+		tools.RegisterPrompts(s)
+
+		if *mcpHTTPMode {
+			srv := httpserver.NewHTTPServer(mgr, cfg)
+			if err := srv.StartMCP(s, subs); err != nil {
+				fmt.Fprintf(os.Stderr, "MCP server error: %v\n", err)
+				exit(1)
+			}
+			exit(0)
+		}
 
-		// Start stdio transport
-		if err := server.ServeStdio(s); err != nil {
+		// Start stdio transport, exiting once stdin closes
+		if err := httpserver.ServeStdio(s, subs); err != nil {
 			fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
-			mgr.Close()
-			os.Exit(1)
+			exit(1)
 		}
+		exit(0)
 	}
 }
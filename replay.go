@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/afalcongonzalez/surfmate.io/internal/browser"
+	"github.com/afalcongonzalez/surfmate.io/internal/config"
+	"github.com/afalcongonzalez/surfmate.io/internal/tools"
+	"github.com/afalcongonzalez/surfmate.io/internal/trace"
+)
+
+// runReplay implements "surfmate.io replay <trace.jsonl>". It exits 0 when the
+// replay matches the trace, 1 when it diverges and 2 on usage or I/O errors.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	keepGoing := fs.Bool("keep-going", false, "Continue after the first divergent step")
+	configPath := config.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: surfmate.io replay [-keep-going] [flags] <trace.jsonl>\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Replay error: %v\n", err)
+		return 2
+	}
+	entries, err := trace.Read(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Replay error: %v\n", err)
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath, fs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		return 2
+	}
+	mgr := browser.GetManager(cfg)
+	defer mgr.Close()
+
+	diverged, err := trace.Replay(entries, mgr, tools.All(mgr), os.Stdout, *keepGoing)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Replay error: %v\n", err)
+		return 2
+	}
+	if diverged > 0 {
+		fmt.Printf("Replay diverged in %d step(s)\n", diverged)
+		return 1
+	}
+	fmt.Printf("Replay matched all %d step(s)\n", len(entries))
+	return 0
+}